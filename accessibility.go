@@ -0,0 +1,163 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Palette is the set of colours the playfield is drawn with
+type Palette struct {
+	Name   string
+	Pacman color.Color
+	Ghosts [4]color.Color // Indexed by ghost variety
+	Walls  []color.Color
+	Cage   color.Color
+	Dot    color.Color
+}
+
+var classicPalette = Palette{
+	Name:   "classic",
+	Pacman: yellow,
+	Ghosts: [4]color.Color{lightBlue, red, green, orange},
+	Walls:  []color.Color{yellow, green, blue, lightBlue, red},
+	Cage:   blue,
+	Dot:    color.White,
+}
+
+// Okabe-Ito colours, distinguishable with protanopia and deuteranopia
+var colourblindPalette = Palette{
+	Name:   "colourblind",
+	Pacman: color.RGBA{240, 228, 66, 255},
+	Ghosts: [4]color.Color{
+		color.RGBA{86, 180, 233, 255},  // Sky blue
+		color.RGBA{213, 94, 0, 255},    // Vermillion
+		color.RGBA{0, 158, 115, 255},   // Bluish green
+		color.RGBA{204, 121, 167, 255}, // Reddish purple
+	},
+	Walls: []color.Color{color.RGBA{0, 114, 178, 255}, color.RGBA{86, 180, 233, 255}},
+	Cage:  color.RGBA{230, 159, 0, 255},
+	Dot:   color.White,
+}
+
+// Avoids blue/yellow pairs, which are confused with tritanopia
+var tritanopiaPalette = Palette{
+	Name:   "tritanopia",
+	Pacman: color.RGBA{255, 255, 255, 255},
+	Ghosts: [4]color.Color{
+		color.RGBA{0, 200, 200, 255},  // Teal
+		color.RGBA{220, 38, 38, 255},  // Red
+		color.RGBA{120, 220, 80, 255}, // Green
+		color.RGBA{236, 90, 170, 255}, // Pink
+	},
+	Walls: []color.Color{color.RGBA{220, 38, 38, 255}, color.RGBA{0, 200, 200, 255}},
+	Cage:  color.RGBA{236, 90, 170, 255},
+	Dot:   color.RGBA{255, 255, 255, 255},
+}
+
+// Pure colours on black for low vision
+var highContrastPalette = Palette{
+	Name:   "high-contrast",
+	Pacman: color.RGBA{255, 255, 0, 255},
+	Ghosts: [4]color.Color{
+		color.RGBA{0, 255, 255, 255},
+		color.RGBA{255, 0, 0, 255},
+		color.RGBA{0, 255, 0, 255},
+		color.RGBA{255, 0, 255, 255},
+	},
+	Walls: []color.Color{color.White},
+	Cage:  color.White,
+	Dot:   color.RGBA{255, 255, 0, 255},
+}
+
+var palettes = []Palette{classicPalette, colourblindPalette, tritanopiaPalette, highContrastPalette}
+
+func findPalette(name string) Palette {
+	// The asset pack's colours are its take on the classic palette
+	if name == classicPalette.Name && currentPack.palette != nil {
+		return *currentPack.palette
+	}
+	for _, p := range palettes {
+		if p.Name == name {
+			return p
+		}
+	}
+	return classicPalette
+}
+
+func paletteNames() []string {
+	return Map(palettes, func(p Palette) string { return p.Name })
+}
+
+func currentPalette() Palette {
+	return findPalette(settings.Accessibility.Palette)
+}
+
+// Recolour everything on the playfield from the current palette
+func (g *Game) applyPalette() {
+	p := currentPalette()
+	g.pacman.color = p.Pacman
+	for i := range g.ghost {
+		g.ghost[i].color = p.Ghosts[g.ghost[i].variety]
+	}
+	for i := range g.walls {
+		g.walls[i].Color = p.Walls[g.walls[i].shade%len(p.Walls)]
+	}
+	g.cage.Top.Color = p.Cage
+	g.cage.Right.Color = p.Cage
+	g.cage.Bottom.Color = p.Cage
+	g.cage.Left.Color = p.Cage
+	for i := range Dots {
+		Dots[i].color = p.Dot
+	}
+}
+
+// F2 cycles the palette, F3-F5 toggle ghost markers, thick walls and
+// high-contrast dots, F8 toggles ghost audio cues
+func (g *Game) handleAccessibilityKeys() {
+	a := &settings.Accessibility
+	changed := true
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyF2):
+		a.Palette = cycleName(paletteNames(), a.Palette, 1)
+	case inpututil.IsKeyJustPressed(ebiten.KeyF3):
+		a.GhostMarkers = !a.GhostMarkers
+	case inpututil.IsKeyJustPressed(ebiten.KeyF4):
+		a.ThickWalls = !a.ThickWalls
+	case inpututil.IsKeyJustPressed(ebiten.KeyF5):
+		a.HighContrastDots = !a.HighContrastDots
+	case inpututil.IsKeyJustPressed(ebiten.KeyF8):
+		a.GhostCues = !a.GhostCues
+	default:
+		changed = false
+	}
+	if !changed {
+		return
+	}
+	g.settingsChanged()
+}
+
+// Draw a shape on the ghost so it can be told apart without colour
+func drawGhostMarker(screen *ebiten.Image, ghost *Pacman) {
+	x, y := float32(ghost.x), float32(ghost.y)
+	size := float32(ghost.radius) / 2
+	markerColor := color.Black
+
+	switch ghost.variety {
+	case CHASER:
+		// Square
+		vector.DrawFilledRect(screen, x-size, y-size, size*2, size*2, markerColor, false)
+	case AMBUSH:
+		// Cross
+		vector.StrokeLine(screen, x-size, y-size, x+size, y+size, 3, markerColor, false)
+		vector.StrokeLine(screen, x-size, y+size, x+size, y-size, 3, markerColor, false)
+	case PATROL:
+		// Horizontal bar
+		vector.DrawFilledRect(screen, x-size, y-size/3, size*2, size*2/3, markerColor, false)
+	case RANDOM:
+		// Ring
+		vector.StrokeCircle(screen, x, y, size, 3, markerColor, false)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import "math"
+
+// Tile-locked movement, as in the arcade. Everything moves along lanes
+// through the nav grid's nodes and only changes direction on a node.
+// Pacman can cut a corner by turning up to corneringDistance early or late;
+// ghosts only pick a new direction at intersections, and never reverse.
+
+// Speed at 100%, in pixels per second. The arcade's own figure is for 8
+// pixel tiles, so it's taken as tiles per second and scaled to ours.
+const arcadeFullSpeed = 75.75757625 / 8 * tileSize
+
+const corneringDistance = 0.4 * tileSize
+
+// Fractions of full speed for a range of levels
+type arcadeSpeeds struct {
+	fromLevel   int
+	pacman      float64
+	ghost       float64
+	ghostTunnel float64
+}
+
+var arcadeSpeedTable = []arcadeSpeeds{
+	{fromLevel: 1, pacman: 0.80, ghost: 0.75, ghostTunnel: 0.40},
+	{fromLevel: 2, pacman: 0.90, ghost: 0.85, ghostTunnel: 0.45},
+	{fromLevel: 5, pacman: 1.00, ghost: 0.95, ghostTunnel: 0.50},
+	{fromLevel: 21, pacman: 0.90, ghost: 0.95, ghostTunnel: 0.50},
+}
+
+var directionVectors = map[Direction]Point{
+	Up:    {x: 0, y: -1},
+	Down:  {x: 0, y: 1},
+	Left:  {x: -1, y: 0},
+	Right: {x: 1, y: 0},
+}
+
+// Arcade tie-break order when two ways are equally good
+var laneDirections = []Point{directionVectors[Up], directionVectors[Left], directionVectors[Down], directionVectors[Right]}
+
+func arcadeSpeedsFor(level int) arcadeSpeeds {
+	speeds := arcadeSpeedTable[0]
+	for _, s := range arcadeSpeedTable {
+		if level >= s.fromLevel {
+			speeds = s
+		}
+	}
+	return speeds
+}
+
+// Pixels per step for a fraction of full speed
+func arcadeSpeed(fraction float64) float64 {
+	return arcadeFullSpeed * fraction * simStep
+}
+
+// How far v is from the nearest lane
+func laneOffset(v float64) float64 {
+	return math.Abs(v - math.Round(v/tileSize)*tileSize)
+}
+
+// Node nearest to x, y, wrapping round for positions out in a tunnel
+func (g *Game) laneNode(x, y float64) int {
+	n := g.nav
+	col := (int(math.Round(x/n.step))%n.cols + n.cols) % n.cols
+	row := (int(math.Round(y/n.step))%n.rows + n.rows) % n.rows
+	return n.index(col, row)
+}
+
+// Whether there is a lane from node i in direction dir
+func (g *Game) laneOpen(i int, dir Point) bool {
+	n := g.nav
+	col, row := i%n.cols+int(dir.x), i/n.cols+int(dir.y)
+	if col >= 0 && row >= 0 && col < n.cols && row < n.rows {
+		return n.open[n.index(col, row)]
+	}
+	// Off the edge, only through a tunnel
+	wrapped := n.index((col+n.cols)%n.cols, (row+n.rows)%n.rows)
+	return n.isLink(i, wrapped)
+}
+
+// Put p on the nearest node if it's further off the lanes than cornering
+// could take it, after spawning or teleporting
+func (g *Game) snapToLane(p *Pacman) {
+	if min(laneOffset(p.x), laneOffset(p.y)) <= corneringDistance {
+		return
+	}
+	if i, ok := g.nav.nearestOpen(p.x, p.y); ok {
+		c := g.nav.centre(i)
+		p.x, p.y = c.x, c.y
+	}
+}
+
+// Move p up to dist along its lane heading dir. On reaching a node, atNode
+// decides which way to go next; a zero direction or a blocked lane stops p
+// there. Returns the direction p ends up heading.
+func (g *Game) moveOnLanes(p *Pacman, dir Point, dist float64, atNode func(node int, dir Point) Point) Point {
+	step := g.nav.step
+	move := func(d float64) {
+		p.x += dir.x * d
+		p.y += dir.y * d
+		// Drift back onto the lane across the way of travel, which is what
+		// makes cornering cut the corner
+		if dir.x != 0 {
+			lane := math.Round(p.y/step) * step
+			p.y += math.Max(-d, math.Min(d, lane-p.y))
+		} else {
+			lane := math.Round(p.x/step) * step
+			p.x += math.Max(-d, math.Min(d, lane-p.x))
+		}
+	}
+
+	for dist > 0 && dir != (Point{}) {
+		along := p.x*dir.x + p.y*dir.y
+		toNode := math.Ceil(along/step-1e-9)*step - along
+		if toNode > dist {
+			move(dist)
+			break
+		}
+		move(toNode)
+		dist -= toNode
+
+		node := g.laneNode(p.x, p.y)
+		dir = atNode(node, dir)
+		if dir == (Point{}) || !g.laneOpen(node, dir) {
+			return Point{}
+		}
+		leave := math.Min(dist, step)
+		move(leave)
+		dist -= leave
+	}
+	return dir
+}
+
+// Move Pacman towards the direction last asked for, turning as soon as the
+// lanes allow
+func (g *Game) movePacmanOnLanes() {
+	p := &g.pacman
+	g.snapToLane(p)
+	want := directionVectors[g.direction]
+	dir := p.lastDir
+
+	speed := arcadeSpeed(arcadeSpeedsFor(g.level).pacman)
+
+	switch {
+	case want == (Point{}):
+	case dir == (Point{}):
+		// Standing still on a node
+		if g.laneOpen(g.laneNode(p.x, p.y), want) {
+			dir = want
+		}
+	case want.x == -dir.x && want.y == -dir.y:
+		// Reversing is allowed anywhere
+		dir = want
+	case want != dir:
+		// Turning is allowed near enough a node, on either side of it
+		node := g.laneNode(p.x, p.y)
+		c := g.nav.centre(node)
+		if math.Abs((p.x-c.x)*dir.x+(p.y-c.y)*dir.y) <= corneringDistance && g.laneOpen(node, want) {
+			dir = want
+		}
+	}
+
+	p.lastDir = g.moveOnLanes(p, dir, speed, func(node int, dir Point) Point {
+		if want != (Point{}) && g.laneOpen(node, want) {
+			return want
+		}
+		return dir
+	})
+}
+
+// Move a ghost along the lanes, choosing a way at each intersection
+func (g *Game) moveGhostOnLanes(p *Pacman, target Point) {
+	g.snapToLane(p)
+	speeds := arcadeSpeedsFor(g.level)
+	fraction := speeds.ghost
+	if g.inTunnel(p.x, p.y) {
+		fraction = speeds.ghostTunnel
+	}
+
+	dir := p.lastDir
+	if dir == (Point{}) {
+		dir = g.chooseLane(p, g.laneNode(p.x, p.y), Point{}, target)
+	}
+	p.lastDir = g.moveOnLanes(p, dir, arcadeSpeed(fraction*currentDifficulty().GhostSpeed), func(node int, dir Point) Point {
+		return g.chooseLane(p, node, dir, target)
+	})
+	if p.lastDir == (Point{}) {
+		// Stopped dead, most likely just spawned; pick a way on the next step
+		p.lastDir = g.chooseLane(p, g.laneNode(p.x, p.y), Point{}, target)
+	}
+}
+
+// The way a ghost takes from a node: never back the way it came unless there
+// is nothing else, then whichever neighbour is nearest the target
+func (g *Game) chooseLane(p *Pacman, node int, dir, target Point) Point {
+	var options []Point
+	for _, d := range laneDirections {
+		if d.x == -dir.x && d.y == -dir.y && dir != (Point{}) {
+			continue
+		}
+		if g.laneOpen(node, d) {
+			options = append(options, d)
+		}
+	}
+	if len(options) == 0 {
+		return Point{x: -dir.x, y: -dir.y}
+	}
+	c := g.nav.centre(node)
+	best, bestDist := options[0], math.Inf(1)
+	for _, d := range options {
+		if dist := distance(c.x+d.x*g.nav.step, c.y+d.y*g.nav.step, target.x, target.y); dist < bestDist {
+			best, bestDist = d, dist
+		}
+	}
+	return best
+}
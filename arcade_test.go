@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+func TestTileLockedGhostTakesTunnel(t *testing.T) {
+	level, err := loadLevel("assets/levels/wide.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	virtualAudio = true
+	settings = defaultSettings()
+	settings.Gameplay.Ruleset = arcadeRules.Name
+	rng.Seed(1)
+	g := newGame(level)
+
+	// One ghost in the left tunnel heading out, with Pacman waiting in the
+	// right one so the tunnel is the short way round
+	g.ghost = g.ghost[:1]
+	ghost := &g.ghost[0]
+	start := g.nav.centre(g.laneNode(g.tunnels[0].centre().x, g.tunnels[0].centre().y))
+	ghost.x, ghost.y = start.x, start.y
+	ghost.lastDir = Point{x: -1}
+	end := g.tunnels[1].centre()
+	g.pacman.x, g.pacman.y = end.x, end.y
+
+	for range 10 * simRate {
+		g.ghostAi(g.ghost)
+		if ghost.x < 0 || ghost.x >= g.worldWidth {
+			t.Fatalf("ghost left the world at x %.1f", ghost.x)
+		}
+		if ghost.x > g.worldWidth/2 {
+			return
+		}
+	}
+	t.Fatalf("ghost didn't come out of the right tunnel, it's at %.1f, %.1f", ghost.x, ghost.y)
+}
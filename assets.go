@@ -0,0 +1,142 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// The default assets are built into the binary, so it runs from anywhere
+//
+//go:embed assets
+var embeddedFiles embed.FS
+
+// overlayFS looks for a file in each layer in turn, so earlier layers
+// replace single files of later ones
+type overlayFS []fs.FS
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	for _, layer := range o {
+		f, err := layer.Open(name)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return f, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// Every image, font and sound is loaded from here, by its path under the
+// assets directory ("img/pacman.png"). Files come from the override
+// directory first, then the current asset pack, then the built-in assets.
+var assets fs.FS = embeddedAssets()
+
+// The override directory, nil if there isn't one
+var (
+	assetOverride    fs.FS
+	assetOverrideDir string
+	overrideModTimes map[string]time.Time // When each file in it was last changed, as of the last look
+)
+
+func buildAssets() {
+	var layers overlayFS
+	if assetOverride != nil {
+		layers = append(layers, assetOverride)
+	}
+	if currentPack.files != nil {
+		layers = append(layers, currentPack.files)
+	}
+	assets = append(layers, embeddedAssets())
+}
+
+func embeddedAssets() fs.FS {
+	sub, err := fs.Sub(embeddedFiles, assetsDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return sub
+}
+
+// Name of the override directory under the config directory, used when
+// -assets isn't given
+const overrideDirName = "assets"
+
+// Load assets from dir where it has them, and the embedded ones otherwise.
+// An empty dir means the override directory in the config directory, if
+// there is one, or in dev mode the assets being worked on, so edits to them
+// show without rebuilding.
+func useAssetOverrides(dir string) error {
+	if dir == "" && devMode {
+		if info, err := os.Stat(assetsDir); err == nil && info.IsDir() {
+			dir = assetsDir
+		}
+	}
+	if dir == "" {
+		config, err := configDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(config, overrideDirName)
+		if _, err := os.Stat(dir); err != nil {
+			return nil
+		}
+	} else if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("asset directory %s not found", dir)
+	}
+	log.Printf("loading assets from %s first", dir)
+	assetOverrideDir = dir
+	assetOverride = os.DirFS(dir)
+	overrideModTimes = overrideFileTimes()
+	buildAssets()
+	return nil
+}
+
+func overrideFileTimes() map[string]time.Time {
+	times := make(map[string]time.Time)
+	fs.WalkDir(assetOverride, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			times[path] = info.ModTime()
+		}
+		return nil
+	})
+	return times
+}
+
+// Files in the override directory added, edited or removed since the last
+// look
+func changedOverrides() []string {
+	times := overrideFileTimes()
+	var changed []string
+	for path, t := range times {
+		if old, ok := overrideModTimes[path]; !ok || !old.Equal(t) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range overrideModTimes {
+		if _, ok := times[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	overrideModTimes = times
+	return changed
+}
+
+func setWindowIcon() error {
+	icon, _, err := ebitenutil.NewImageFromFileSystem(assets, assetPath(imageDir+"pacman.png"))
+	if err != nil {
+		return err
+	}
+	ebiten.SetWindowIcon([]image.Image{icon})
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Put a copy of the built-in file from in the override directory at to
+func writeOverride(t *testing.T, dir, from, to string) []byte {
+	t.Helper()
+	data, err := fs.ReadFile(embeddedAssets(), from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, to)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Load assets from dir for the rest of the test
+func useTestOverrides(t *testing.T, dir string) {
+	t.Helper()
+	if err := useAssetOverrides(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		assetOverride, assetOverrideDir = nil, ""
+		buildAssets()
+	})
+}
+
+func TestReloadOverridesKeepsCache(t *testing.T) {
+	g := newTestGame(t)
+	dir := t.TempDir()
+	dot, siren := sounds[soundDot].file, sounds[soundSiren].file
+	writeOverride(t, dir, dot, dot)
+	useTestOverrides(t, dir)
+	for _, path := range []string{dot, siren} {
+		if _, err := loadAudio(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cachedSiren, _ := audioCache.Get(assetKey(siren))
+
+	// Replace the dot sound, with a later time so it's seen to change
+	edited := writeOverride(t, dir, sounds[soundGameOver].file, dot)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, dot), later, later)
+	if err := g.reloadOverrides(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadAudio(dot); err != nil {
+		t.Fatal(err)
+	}
+	if cached, ok := audioCache.Get(assetKey(dot)); !ok || !bytes.Equal(cached.data, edited) {
+		t.Error("edited override still cached as it was")
+	}
+	if cached, ok := audioCache.Get(assetKey(siren)); !ok || cached != cachedSiren {
+		t.Error("unchanged sound was loaded again")
+	}
+}
@@ -2,13 +2,17 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
-	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
-	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
 	"github.com/hajimehoshi/ebiten/v2/audio/wav"
 	lru "github.com/hashicorp/golang-lru/v2"
 )
@@ -36,28 +40,111 @@ func init() {
 	}
 }
 
-func initializeAudioPlayerWithContext(path string, context *audio.Context) (*audio.Player, error) {
-	// Check cache
-	if cached, ok := audioCache.Get(path); ok {
-		log.Println("Audio player cache hit")
-		reader := cached.NewReader()
-		decodedWav, err := wav.Decode(context, reader)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode cached WAV data: %w", err)
+// Files bigger than this are streamed from disk rather than read into the
+// cache, so long music doesn't sit in memory
+const maxCachedAudioSize = 1 << 20
+
+// A decoded sound, as the player and loops need it
+type audioStream interface {
+	io.ReadSeeker
+	Length() int64
+}
+
+// A stream decoded from a file as it plays, which owns the file
+type fileStream struct {
+	audioStream
+	file io.Closer
+}
+
+func (f fileStream) Close() error {
+	return f.file.Close()
+}
+
+// Sound formats, recognised by extension or failing that by their first bytes
+var audioFormats = []struct {
+	ext    string
+	magic  [][]byte
+	decode func(io.Reader) (audioStream, error)
+}{
+	{".wav", [][]byte{[]byte("RIFF")}, func(r io.Reader) (audioStream, error) { return wav.DecodeWithSampleRate(sampleRate, r) }},
+	{".ogg", [][]byte{[]byte("OggS")}, func(r io.Reader) (audioStream, error) { return vorbis.DecodeWithSampleRate(sampleRate, r) }},
+	{".mp3", [][]byte{[]byte("ID3"), {0xff, 0xfb}, {0xff, 0xf3}, {0xff, 0xf2}}, func(r io.Reader) (audioStream, error) { return mp3.DecodeWithSampleRate(sampleRate, r) }},
+}
+
+// Decode src with the decoder for path's extension, or the one its contents
+// look like
+func decodeAudio(path string, src io.ReadSeeker) (audioStream, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, f := range audioFormats {
+		if f.ext == ext {
+			return f.decode(src)
+		}
+	}
+
+	header := make([]byte, 4)
+	n, err := io.ReadFull(src, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	for _, f := range audioFormats {
+		for _, magic := range f.magic {
+			if bytes.HasPrefix(header[:n], magic) {
+				return f.decode(src)
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s is not WAV, Ogg Vorbis or MP3", path)
+}
+
+// The sound file to use for path: path itself, or if that's missing the same
+// name in another format, so an asset can be swapped for a compressed one
+func findAudioFile(path string) string {
+	if _, err := fs.Stat(assets, path); err == nil {
+		return path
+	}
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, f := range audioFormats {
+		if _, err := fs.Stat(assets, base+f.ext); err == nil {
+			return base + f.ext
 		}
+	}
+	return path
+}
 
-		audioPlayer, err := context.NewPlayer(decodedWav)
+// Decode a sound file at the game's sample rate, reading it from the cache if it's been loaded before.
+// Big files are decoded straight from disk as they play.
+func loadAudio(path string) (audioStream, error) {
+	path = findAudioFile(assetPath(path))
+	// Check cache
+	if cached, ok := audioCache.Get(assetKey(path)); ok {
+		stream, err := decodeAudio(path, cached.NewReader())
 		if err != nil {
-			return nil, fmt.Errorf("failed to create audio player from cache: %w", err)
+			return nil, fmt.Errorf("failed to decode cached audio data: %w", err)
 		}
-		return audioPlayer, nil
+		return stream, nil
 	}
 
-	// Load and decode the audio file
-	file, err := os.Open(path)
+	file, err := assets.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open audio file: %w", err)
 	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read audio file: %w", err)
+	}
+	if seeker, ok := file.(io.ReadSeeker); ok && info.Size() > maxCachedAudioSize {
+		// The player reads from the file until it's closed
+		stream, err := decodeAudio(path, seeker)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		return fileStream{stream, file}, nil
+	}
 	defer file.Close()
 
 	// Read the entire file into memory
@@ -67,20 +154,11 @@ func initializeAudioPlayerWithContext(path string, context *audio.Context) (*aud
 	}
 
 	// Cache the raw audio data
-	audioCache.Add(path, &AudioData{data: data})
+	audioCache.Add(assetKey(path), &AudioData{data: data})
 
-	// Create a new reader from the data for immediate use
-	reader := bytes.NewReader(data)
-	decodedWav, err := wav.Decode(context, reader)
+	stream, err := decodeAudio(path, bytes.NewReader(data))
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode WAV file: %w", err)
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
 	}
-
-	// Create a new audio player
-	audioPlayer, err := context.NewPlayer(decodedWav)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create audio player: %w", err)
-	}
-
-	return audioPlayer, nil
+	return stream, nil
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// audioBackend makes the players sounds play on. The ebiten backend plays
+// through the sound device; the virtual one only keeps time, so runs without
+// one (and tests) see sounds start and finish exactly when they would.
+type audioBackend interface {
+	// A player for 16-bit stereo PCM at sampleRate. Length is in bytes, 0
+	// for a stream that never ends.
+	newPlayer(src io.ReadSeeker, length int64) (audioPlayer, error)
+	// Move the clock on by dt seconds of play
+	advance(dt float64)
+}
+
+// audioPlayer is what the game needs of a player, which *audio.Player has
+type audioPlayer interface {
+	Play()
+	Pause()
+	IsPlaying() bool
+	Rewind() error
+	SetVolume(volume float64)
+	Close() error
+}
+
+// Simulate audio instead of playing it, set by -virtual-audio
+var virtualAudio bool
+
+func newAudioBackend() audioBackend {
+	if virtualAudio {
+		return &virtualBackend{}
+	}
+	return ebitenBackend{context: audio.NewContext(sampleRate)}
+}
+
+type ebitenBackend struct {
+	context *audio.Context
+}
+
+func (e ebitenBackend) newPlayer(src io.ReadSeeker, length int64) (audioPlayer, error) {
+	return e.context.NewPlayer(src)
+}
+
+// The sound device keeps its own time
+func (e ebitenBackend) advance(dt float64) {}
+
+// virtualBackend plays nothing, but its players run for as long as their
+// sound lasts, on a clock the game moves on
+type virtualBackend struct {
+	players []*virtualPlayer
+	mu      sync.Mutex
+}
+
+type virtualPlayer struct {
+	backend  *virtualBackend
+	duration float64 // Seconds, 0 if it never ends
+	position float64
+	playing  bool
+	volume   float64
+}
+
+func (v *virtualBackend) newPlayer(src io.ReadSeeker, length int64) (audioPlayer, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	p := &virtualPlayer{backend: v, duration: float64(length) / (4 * sampleRate), volume: 1}
+	v.players = append(v.players, p)
+	return p, nil
+}
+
+func (v *virtualBackend) advance(dt float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, p := range v.players {
+		if !p.playing {
+			continue
+		}
+		p.position += dt
+		if p.duration > 0 && p.position >= p.duration {
+			p.position = p.duration
+			p.playing = false
+		}
+	}
+}
+
+// Like an ebiten player, one that's reached the end stays there until rewound
+func (p *virtualPlayer) Play() {
+	p.playing = p.duration == 0 || p.position < p.duration
+}
+
+func (p *virtualPlayer) Pause() {
+	p.playing = false
+}
+
+func (p *virtualPlayer) IsPlaying() bool {
+	return p.playing
+}
+
+func (p *virtualPlayer) Rewind() error {
+	p.position = 0
+	return nil
+}
+
+func (p *virtualPlayer) SetVolume(volume float64) {
+	p.volume = volume
+}
+
+// Stop keeping time for the player. Nothing else to free, as nothing was
+// really playing.
+func (p *virtualPlayer) Close() error {
+	v := p.backend
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	p.playing = false
+	v.players = slices.DeleteFunc(v.players, func(other *virtualPlayer) bool { return other == p })
+	return nil
+}
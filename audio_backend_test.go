@@ -0,0 +1,135 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// A game that plays no sound but keeps time for it, as with -virtual-audio
+func newTestGame(t *testing.T) *Game {
+	t.Helper()
+	virtualAudio = true
+	settings = defaultSettings()
+	rng.Seed(1)
+	return newGame(&defaultLevel)
+}
+
+// Seconds a sound file lasts
+func soundLength(t *testing.T, event soundEvent) float64 {
+	t.Helper()
+	stream, err := loadAudio(sounds[event].file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return float64(stream.Length()) / (4 * sampleRate)
+}
+
+// Steps run until done is true, failing after limit seconds
+func stepsUntil(t *testing.T, g *Game, limit float64, done func() bool) int {
+	t.Helper()
+	for steps := 1; steps <= int(limit*simRate); steps++ {
+		g.advance(simStep)
+		if done() {
+			return steps
+		}
+	}
+	t.Fatalf("still waiting after %gs", limit)
+	return 0
+}
+
+// A sound is heard to its end, so play carries on within a step of it
+func assertLasts(t *testing.T, what string, steps int, seconds float64) {
+	t.Helper()
+	want := int(math.Ceil(seconds * simRate))
+	if steps < want || steps > want+1 {
+		t.Errorf("%s lasted %d steps, want %d or %d for %.3fs", what, steps, want, want+1, seconds)
+	}
+}
+
+func TestIntroLength(t *testing.T) {
+	var runs []int
+	for range 2 {
+		g := newTestGame(t)
+		g.start()
+		if !g.introMusicPlaying {
+			t.Fatal("intro isn't playing after starting")
+		}
+		steps := stepsUntil(t, g, 10, func() bool { return !g.introMusicPlaying })
+		assertLasts(t, "intro", steps, soundLength(t, soundIntro))
+		runs = append(runs, steps)
+	}
+	if runs[0] != runs[1] {
+		t.Errorf("intro lasted %d steps, then %d", runs[0], runs[1])
+	}
+}
+
+func TestDeathWaitsForJingle(t *testing.T) {
+	g := newTestGame(t)
+	g.start()
+	stepsUntil(t, g, 10, func() bool { return !g.introMusicPlaying })
+
+	// Put a ghost on Pacman
+	g.ghost[0].x, g.ghost[0].y = g.pacman.x, g.pacman.y
+	lives := g.livesLeft
+	g.advance(simStep)
+	if g.livesLeft != lives-1 || g.afterJingle == nil {
+		t.Fatalf("lives %d after being caught with %d, waiting for jingle %v", g.livesLeft, lives, g.afterJingle != nil)
+	}
+
+	steps := stepsUntil(t, g, 10, func() bool { return g.afterJingle == nil })
+	assertLasts(t, "death", steps, soundLength(t, soundDeath))
+	if !g.introMusicPlaying || g.pacman.x != g.spawn.x || g.pacman.y != g.spawn.y {
+		t.Error("pacman wasn't respawned after the jingle")
+	}
+}
+
+func TestVirtualPlayer(t *testing.T) {
+	backend := &virtualBackend{}
+	p, err := backend.newPlayer(nil, 4*sampleRate) // A second of sound
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Play()
+	backend.advance(0.5)
+	if !p.IsPlaying() {
+		t.Fatal("stopped half way through")
+	}
+	p.Pause()
+	backend.advance(1)
+	p.Play()
+	backend.advance(0.49)
+	if !p.IsPlaying() {
+		t.Fatal("time passed while paused")
+	}
+	backend.advance(0.02)
+	if p.IsPlaying() {
+		t.Fatal("still playing past the end")
+	}
+	if p.Play(); p.IsPlaying() {
+		t.Error("played again from the end without rewinding")
+	}
+	p.Rewind()
+	if p.Play(); !p.IsPlaying() {
+		t.Error("didn't play after rewinding")
+	}
+}
+
+func TestVirtualPlayerClose(t *testing.T) {
+	backend := &virtualBackend{}
+	var players []audioPlayer
+	for range 3 {
+		p, err := backend.newPlayer(nil, 4*sampleRate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, p)
+	}
+	players[1].Close()
+	if len(backend.players) != 2 {
+		t.Errorf("%d players kept after closing one of 3", len(backend.players))
+	}
+	if slices.Contains(backend.players, players[1].(*virtualPlayer)) {
+		t.Error("closed player is still kept")
+	}
+}
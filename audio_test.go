@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDecodeAudioPicksFormat(t *testing.T) {
+	// Decoders that only note which format was picked and what they were given
+	var picked string
+	var given []byte
+	saved := audioFormats
+	audioFormats = slices.Clone(saved)
+	defer func() { audioFormats = saved }()
+	for i := range audioFormats {
+		ext := audioFormats[i].ext
+		audioFormats[i].decode = func(r io.Reader) (audioStream, error) {
+			picked = ext
+			var err error
+			given, err = io.ReadAll(r)
+			return nil, err
+		}
+	}
+
+	tests := []struct {
+		path string
+		data string
+		want string // Empty if it shouldn't decode
+	}{
+		{"a.ogg", "RIFF....", ".ogg"}, // The extension wins over the contents
+		{"a.MP3", "OggS....", ".mp3"},
+		{"a.wav", "OggS....", ".wav"},
+		{"a", "RIFF....", ".wav"},
+		{"a.snd", "OggS....", ".ogg"},
+		{"a.snd", "ID3.....", ".mp3"},
+		{"a.snd", "\xff\xfb....", ".mp3"},
+		{"a.snd", "\xff\xf3....", ".mp3"},
+		{"a.snd", "junk....", ""},
+		{"a.snd", "ab", ""},
+	}
+	for _, test := range tests {
+		picked, given = "", nil
+		_, err := decodeAudio(test.path, bytes.NewReader([]byte(test.data)))
+		switch {
+		case test.want == "" && err == nil:
+			t.Errorf("%s %q: decoded as %s", test.path, test.data, picked)
+		case test.want != "" && picked != test.want:
+			t.Errorf("%s %q: decoded as %q, want %s", test.path, test.data, picked, test.want)
+		case test.want != "" && string(given) != test.data:
+			// Sniffing the contents mustn't lose the start of the file
+			t.Errorf("%s %q: decoder was given %q", test.path, test.data, given)
+		}
+	}
+}
+
+// A silent WAV file with size bytes of 16-bit stereo samples
+func silentWAV(size int) []byte {
+	var b bytes.Buffer
+	le := func(v any) { binary.Write(&b, binary.LittleEndian, v) }
+	b.WriteString("RIFF")
+	le(uint32(36 + size))
+	b.WriteString("WAVEfmt ")
+	le(uint32(16))
+	le(uint16(1)) // PCM
+	le(uint16(2))
+	le(uint32(sampleRate))
+	le(uint32(sampleRate * 4))
+	le(uint16(4))
+	le(uint16(16))
+	b.WriteString("data")
+	le(uint32(size))
+	b.Write(make([]byte, size))
+	return b.Bytes()
+}
+
+func TestLoadAudioStreamsBigFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, audioDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sizes := map[string]int{"small.wav": 1 << 10, "big.wav": maxCachedAudioSize + 1<<10}
+	for name, size := range sizes {
+		if err := os.WriteFile(filepath.Join(dir, audioDir, name), silentWAV(size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	useTestOverrides(t, dir)
+
+	for name, size := range sizes {
+		path := audioDir + name
+		stream, err := loadAudio(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stream.Length() != int64(size) {
+			t.Errorf("%s: %d bytes long, want %d", name, stream.Length(), size)
+		}
+		file, streamed := stream.(fileStream)
+		if streamed {
+			file.Close()
+		}
+		if big := size > maxCachedAudioSize; streamed != big {
+			t.Errorf("%s: streamed %v for %d bytes", name, streamed, size)
+		}
+		if _, cached := audioCache.Get(assetKey(path)); cached == streamed {
+			t.Errorf("%s: cached %v, streamed %v", name, cached, streamed)
+		}
+	}
+}
@@ -8,3 +8,13 @@ func (c *Square) Draw(screen *ebiten.Image) {
 	c.Bottom.Draw(screen)
 	c.Left.Draw(screen)
 }
+
+// Build the ghost house walls around a rectangle
+func newCage(r Wall) Square {
+	return Square{
+		Top:    Wall{x: r.x, y: r.y, Width: r.Width, Height: wallThickness},
+		Right:  Wall{x: r.x + r.Width - wallThickness, y: r.y, Width: wallThickness, Height: r.Height},
+		Bottom: Wall{x: r.x, y: r.y + r.Height - wallThickness, Width: r.Width, Height: wallThickness},
+		Left:   Wall{x: r.x, y: r.y, Width: wallThickness, Height: r.Height},
+	}
+}
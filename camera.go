@@ -0,0 +1,54 @@
+package main
+
+import "math"
+
+// Camera is the part of the world shown on screen, by its top left corner
+type Camera struct {
+	x, y float64
+}
+
+const (
+	// Pacman can move this far from the centre of the view before it scrolls
+	cameraDeadZoneX = screenWidth / 8
+	cameraDeadZoneY = screenHeight / 8
+	// Rate the camera closes the remaining distance, per second
+	cameraCatchUp = 5.0
+)
+
+// Ease the camera towards keeping the target inside the dead zone, once per step
+func (c *Camera) Follow(target Point, worldWidth, worldHeight float64) {
+	x := deadZone(c.x+screenWidth/2, target.x, cameraDeadZoneX) - screenWidth/2
+	y := deadZone(c.y+screenHeight/2, target.y, cameraDeadZoneY) - screenHeight/2
+	x, y = clampView(x, y, worldWidth, worldHeight)
+
+	smoothing := 1 - math.Exp(-cameraCatchUp*simStep)
+	c.x += (x - c.x) * smoothing
+	c.y += (y - c.y) * smoothing
+}
+
+// Centre the camera on the target straight away, e.g. after a respawn
+func (c *Camera) Snap(target Point, worldWidth, worldHeight float64) {
+	c.x, c.y = clampView(target.x-screenWidth/2, target.y-screenHeight/2, worldWidth, worldHeight)
+}
+
+// Move centre just enough that target is within margin of it
+func deadZone(centre, target, margin float64) float64 {
+	switch {
+	case target > centre+margin:
+		return target - margin
+	case target < centre-margin:
+		return target + margin
+	}
+	return centre
+}
+
+// Keep the view inside the world, centring worlds smaller than the screen
+func clampView(x, y, worldWidth, worldHeight float64) (float64, float64) {
+	clamp := func(v, world, view float64) float64 {
+		if world <= view {
+			return (world - view) / 2
+		}
+		return math.Max(0, math.Min(v, world-view))
+	}
+	return clamp(x, worldWidth, screenWidth), clamp(y, worldHeight, screenHeight)
+}
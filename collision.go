@@ -0,0 +1,32 @@
+package main
+
+import "math"
+
+// Longest distance moved between collision checks. Half the thinnest wall,
+// so nothing can step clean over one however fast it goes.
+const sweepStep = tileSize / 2
+
+// Whether a circle overlaps a rectangle, going by the nearest point of the
+// rectangle to the circle's centre
+func circleIntersectsRect(cx, cy, r, x, y, w, h float64) bool {
+	dx := cx - math.Max(x, math.Min(cx, x+w))
+	dy := cy - math.Max(y, math.Min(cy, y+h))
+	return dx*dx+dy*dy < r*r
+}
+
+func circlesOverlap(a, b *Pacman) bool {
+	return distance(a.x, a.y, b.x, b.y) < a.radius+b.radius
+}
+
+// Whether a body of radius r moving in a straight line from x0, y0 to
+// x1, y1 hits a wall or the cage anywhere along the way
+func (g *Game) sweptCollision(x0, y0, x1, y1, r float64) bool {
+	steps := max(int(math.Ceil(distance(x0, y0, x1, y1)/sweepStep)), 1)
+	for i := 1; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		if g.anyCollision(x0+(x1-x0)*t, y0+(y1-y0)*t, r) {
+			return true
+		}
+	}
+	return false
+}
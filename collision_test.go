@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestCircleIntersectsRect(t *testing.T) {
+	// A 10 by 10 rectangle at the origin, against circles of radius 2
+	tests := []struct {
+		name   string
+		x, y   float64
+		inside bool
+	}{
+		{"centre inside", 5, 5, true},
+		{"overlapping an edge", 11, 5, true},
+		{"clear of an edge", 12.5, 5, false},
+		{"touching an edge", 12, 5, false},
+		{"overlapping a corner", 11, 11, true},
+		// A square twice the radius across would catch this one
+		{"beside a corner", 11.5, 11.5, false},
+	}
+	for _, test := range tests {
+		if got := circleIntersectsRect(test.x, test.y, 2, 0, 0, 10, 10); got != test.inside {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.inside)
+		}
+	}
+}
+
+func TestCirclesOverlap(t *testing.T) {
+	a := Pacman{x: 0, y: 0, radius: 3}
+	b := Pacman{x: 4, y: 0, radius: 2}
+	if !circlesOverlap(&a, &b) {
+		t.Error("circles closer than their radii added together don't overlap")
+	}
+	b.x = 5
+	if circlesOverlap(&a, &b) {
+		t.Error("touching circles overlap")
+	}
+}
+
+func TestSweptCollision(t *testing.T) {
+	// A one tile wall down the middle, with the cage tucked in a corner
+	g := &Game{}
+	g.loadMaze(&Level{
+		Cols:  20,
+		Rows:  20,
+		Walls: []TileRect{{Col: 10, Row: 0, Cols: 1, Rows: 20}},
+		Cage:  TileRect{Col: 0, Row: 16, Cols: 4, Rows: 4},
+	})
+	r := tileSize / 2.0
+	y := 8.0 * tileSize
+	left, right := 8.0*tileSize, 13.0*tileSize
+	if g.anyCollision(left, y, r) || g.anyCollision(right, y, r) {
+		t.Fatal("test positions aren't clear of the wall")
+	}
+	if !g.sweptCollision(left, y, right, y, r) {
+		t.Error("stepped clean over the wall")
+	}
+	if g.sweptCollision(left, y, left, y+4*tileSize, r) {
+		t.Error("hit a wall moving alongside it")
+	}
+}
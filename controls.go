@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Bindings are the keys for each action, by ebiten key name ("ArrowUp",
+// "W", "Escape"). Any of an action's keys will do.
+type Bindings struct {
+	Up    []string `json:"up"`
+	Down  []string `json:"down"`
+	Left  []string `json:"left"`
+	Right []string `json:"right"`
+	Pause []string `json:"pause"`
+	Mute  []string `json:"mute"`
+}
+
+// maxBindingKeys is how many keys one action can have
+const maxBindingKeys = 3
+
+func defaultBindings() Bindings {
+	return Bindings{
+		Up:    []string{"ArrowUp", "W"},
+		Down:  []string{"ArrowDown", "S"},
+		Left:  []string{"ArrowLeft", "A"},
+		Right: []string{"ArrowRight", "D"},
+		Pause: []string{"Escape", "P"},
+		Mute:  []string{"M"},
+	}
+}
+
+// binding is one action's name and keys, for going through them all
+type binding struct {
+	action string
+	keys   *[]string
+}
+
+func (b *Bindings) all() []binding {
+	return []binding{
+		{"Up", &b.Up},
+		{"Down", &b.Down},
+		{"Left", &b.Left},
+		{"Right", &b.Right},
+		{"Pause", &b.Pause},
+		{"Mute", &b.Mute},
+	}
+}
+
+func parseKey(name string) (ebiten.Key, bool) {
+	var k ebiten.Key
+	err := k.UnmarshalText([]byte(name))
+	return k, err == nil
+}
+
+// Drop unknown key names, and give any action left without keys its defaults
+func (b *Bindings) validate() []string {
+	var problems []string
+	defaults := defaultBindings()
+	for i, action := range b.all() {
+		var keys []string
+		for _, name := range *action.keys {
+			if _, ok := parseKey(name); ok {
+				keys = append(keys, name)
+			} else {
+				problems = append(problems, fmt.Sprintf("unknown key %q for %s", name, action.action))
+			}
+		}
+		if len(keys) == 0 {
+			problems = append(problems, fmt.Sprintf("nothing bound to %s, using the defaults", action.action))
+			keys = *defaults.all()[i].keys
+		}
+		*action.keys = keys[:min(len(keys), maxBindingKeys)]
+	}
+	return problems
+}
+
+// Whether any of the keys is held down
+func keyDown(keys []string) bool {
+	for _, name := range keys {
+		if k, ok := parseKey(name); ok && ebiten.IsKeyPressed(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether any of the keys went down this frame
+func keyJustPressed(keys []string) bool {
+	for _, name := range keys {
+		if k, ok := parseKey(name); ok && inpututil.IsKeyJustPressed(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func keyNames(keys []string) string {
+	return strings.Join(keys, "/")
+}
+
+// Wait for the next key press to bind to an action, in place of its other keys
+func (g *Game) rebind(keys *[]string) {
+	g.rebinding = keys
+}
+
+// Bind the first key pressed to the action waiting for one. Escape cancels.
+func (g *Game) updateRebind() {
+	keys := inpututil.AppendJustPressedKeys(nil)
+	if len(keys) == 0 {
+		return
+	}
+	if keys[0] != ebiten.KeyEscape {
+		*g.rebinding = []string{keys[0].String()}
+		g.settingsChanged()
+	}
+	g.rebinding = nil
+}
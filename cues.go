@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// Audio cues: each ghost pulses a quiet tone of its own, panned to the side
+// it's on and louder the closer it gets, so ghosts can be heard coming
+// without seeing them.
+
+const (
+	cueVolume  = 0.4              // Loudest a cue gets, right on top of Pacman
+	cueRange   = 24 * tileSize    // Ghosts further away than this can't be heard
+	cueLatency = time.Second / 20 // Small player buffer, so panning keeps up
+)
+
+// Cue notes by ghost variety, a chord so they're told apart but sit together
+var cueNotes = [4]int{72, 76, 79, 83}
+
+// pannedStream plays a 16-bit stereo stream with separate left and right
+// gains, which can change while it plays
+type pannedStream struct {
+	src         io.ReadSeeker
+	mu          sync.Mutex
+	left, right float64
+}
+
+func (p *pannedStream) Read(b []byte) (int, error) {
+	n, err := p.src.Read(b)
+	p.mu.Lock()
+	left, right := p.left, p.right
+	p.mu.Unlock()
+	for i := 0; i+4 <= n; i += 4 {
+		l := float64(int16(binary.LittleEndian.Uint16(b[i:])))
+		r := float64(int16(binary.LittleEndian.Uint16(b[i+2:])))
+		binary.LittleEndian.PutUint16(b[i:], uint16(int16(l*left)))
+		binary.LittleEndian.PutUint16(b[i+2:], uint16(int16(r*right)))
+	}
+	return n, err
+}
+
+func (p *pannedStream) Seek(offset int64, whence int) (int64, error) {
+	return p.src.Seek(offset, whence)
+}
+
+// Set the gains for a position from -1 (left) to 1 (right), keeping the
+// overall loudness the same wherever it is
+func (p *pannedStream) pan(position, volume float64) {
+	angle := (position + 1) * math.Pi / 4
+	p.mu.Lock()
+	p.left, p.right = math.Cos(angle)*volume, math.Sin(angle)*volume
+	p.mu.Unlock()
+}
+
+// A ghost's cue and the player it plays on
+type ghostCue struct {
+	stream *pannedStream
+	player audioPlayer
+}
+
+func newGhostCue(backend audioBackend, note int) (*ghostCue, error) {
+	data := render(triangle, 1, false, tone{midi(note), midi(note), 0.12}, tone{seconds: 0.38})
+	loop := audio.NewInfiniteLoop(pcmStream{bytes.NewReader(data)}, int64(len(data)))
+	stream := &pannedStream{src: loop}
+	player, err := backend.newPlayer(stream, 0)
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := player.(*audio.Player); ok {
+		p.SetBufferSize(cueLatency)
+	}
+	return &ghostCue{stream: stream, player: player}, nil
+}
+
+// Whether the cues should be sounding: turned on, and a game in play
+func (g *Game) cuesAudible() bool {
+	return settings.Accessibility.GhostCues && len(g.menus) == 0 && !g.onTitle && !g.editing &&
+		!g.gameOverState && !g.introMusicPlaying && g.afterJingle == nil
+}
+
+// Pan and set the volume of every ghost's cue from where it is relative to
+// Pacman
+func (g *Game) updateCues() {
+	if !g.cuesAudible() {
+		for _, cue := range g.cues {
+			cue.player.Pause()
+		}
+		return
+	}
+	for len(g.cues) < len(g.ghost) {
+		cue, err := newGhostCue(g.sounds.backend, cueNotes[g.ghost[len(g.cues)].variety%len(cueNotes)])
+		if err != nil {
+			log.Printf("failed to create ghost cue: %v", err)
+			settings.Accessibility.GhostCues = false
+			return
+		}
+		g.cues = append(g.cues, cue)
+	}
+
+	for i, cue := range g.cues {
+		if i >= len(g.ghost) {
+			cue.player.Pause()
+			continue
+		}
+		ghost := &g.ghost[i]
+		dx := ghost.x - g.pacman.x
+		closeness := max(1-distance(ghost.x, ghost.y, g.pacman.x, g.pacman.y)/cueRange, 0)
+		volume := cueVolume * closeness * closeness * g.sounds.volume(channelEffects)
+		cue.stream.pan(min(max(dx/(cueRange/2), -1), 1), volume)
+		if !cue.player.IsPlaying() {
+			cue.player.Play()
+		}
+	}
+}
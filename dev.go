@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+)
+
+// Dev mode reloads the level file, the asset override directory, the
+// current asset pack and the tunables as they're edited, without restarting
+// the game. A reload that fails leaves things as they were and shows why
+// on screen until it's fixed.
+
+// Set by -dev
+var devMode bool
+
+// How often to look for edited files in dev mode
+const devCheckInterval = 0.5
+
+var (
+	devModified  = make(map[string]time.Time) // When each watched path last changed
+	devCheckTime float64
+)
+
+// A file or directory to watch, and what to do when it changes
+type watchedPath struct {
+	path   string
+	reload func() error
+}
+
+func (g *Game) watchedPaths() []watchedPath {
+	var watched []watchedPath
+	if tunablesPath != "" {
+		watched = append(watched, watchedPath{tunablesPath, g.reloadTunables})
+	}
+	if g.mazePath != "" {
+		watched = append(watched, watchedPath{g.mazePath, g.reloadLevel})
+	}
+	if assetOverrideDir != "" {
+		watched = append(watched, watchedPath{assetOverrideDir, g.reloadOverrides})
+	}
+	if currentPack.source != "" {
+		watched = append(watched, watchedPath{currentPack.source, g.reloadPack})
+	}
+	return watched
+}
+
+// When anything at path last changed: the file, or for a directory the
+// latest of everything in it, which also catches files being removed
+func lastChange(path string) (time.Time, error) {
+	var latest time.Time
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+	return latest, err
+}
+
+// Reload anything watched that's changed since the last look. Paths are
+// only noted the first time they're seen, having just been loaded.
+func (g *Game) watchDevFiles(dt float64) {
+	if !devMode {
+		return
+	}
+	devCheckTime += dt
+	if devCheckTime < devCheckInterval {
+		return
+	}
+	devCheckTime = 0
+
+	for _, w := range g.watchedPaths() {
+		changed, err := lastChange(w.path)
+		if err != nil {
+			continue
+		}
+		last, seen := devModified[w.path]
+		devModified[w.path] = changed
+		if !seen || !changed.After(last) {
+			continue
+		}
+		if err := w.reload(); err != nil {
+			log.Printf("failed to reload %s: %v", w.path, err)
+			g.reloadErrors[w.path] = err
+			continue
+		}
+		log.Printf("reloaded %s", w.path)
+		delete(g.reloadErrors, w.path)
+	}
+}
+
+func (g *Game) reloadTunables() error {
+	t, err := loadTunables(tunablesPath)
+	if err != nil {
+		return err
+	}
+	g.applyTunables(t)
+	return nil
+}
+
+// Swap in the edited level, keeping the score, lives and level reached.
+// Everything goes back to its spawn, as the old positions may now be walls.
+func (g *Game) reloadLevel() error {
+	level, err := loadLevel(g.mazePath)
+	if err != nil {
+		return err
+	}
+	if problems := checkLevel(level); len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+	g.classicMaze = level
+	if g.mode == modeClassic && !g.editing {
+		g.useLevel(level)
+		g.catchUpGhostSpeeds()
+	}
+	return nil
+}
+
+// Load what's changed in the override directory, keeping everything else
+// that's cached
+func (g *Game) reloadOverrides() error {
+	for _, path := range changedOverrides() {
+		forgetAssets(func(key string) bool { return strings.HasSuffix(key, ":"+path) })
+	}
+	return g.reloadAssets()
+}
+
+// Load the current pack again from where it came from
+func (g *Game) reloadPack() error {
+	pack, err := openPack(currentPack.source)
+	if err != nil {
+		return err
+	}
+	forgetAssets(func(key string) bool { return strings.HasPrefix(key, currentPack.name+":") })
+	if currentPack.archive != nil {
+		currentPack.archive.Close()
+	}
+	for i, p := range packs {
+		if p == currentPack {
+			packs[i] = pack
+		}
+	}
+	settings.Display.AssetPack = pack.name
+	selectPack(pack.name)
+	if err := g.reloadAssets(); err != nil {
+		return err
+	}
+	g.applyPalette()
+	return nil
+}
+
+// Errors from dev mode reloads, over everything else
+func (g *Game) drawReloadErrors(screen *ebiten.Image) {
+	paths := make([]string, 0, len(g.reloadErrors))
+	for path := range g.reloadErrors {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	y := screenHeight - 18
+	for _, path := range paths {
+		ebitenutil.DebugPrintAt(screen, path+": "+g.reloadErrors[path].Error(), 4, y)
+		y -= 16
+	}
+}
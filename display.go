@@ -0,0 +1,108 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// The screen is the window at device resolution, the playfield is scaled into it
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	scale := ebiten.Monitor().DeviceScaleFactor()
+	g.screenWidth = int(math.Ceil(float64(outsideWidth) * scale))
+	g.screenHeight = int(math.Ceil(float64(outsideHeight) * scale))
+	return g.screenWidth, g.screenHeight
+}
+
+// How the logical canvas is scaled and offset to sit centred on the screen
+func canvasTransform(screenW, screenH int) (scale, offsetX, offsetY float64) {
+	sw, sh := float64(screenW), float64(screenH)
+	scale = math.Min(sw/screenWidth, sh/screenHeight)
+	if settings.Display.IntegerScale && scale >= 1 {
+		// Whole multiples keep every logical pixel the same size
+		scale = math.Floor(scale)
+	}
+	return scale, math.Floor((sw - screenWidth*scale) / 2), math.Floor((sh - screenHeight*scale) / 2)
+}
+
+// Draw the logical canvas centred on the screen, letterboxing whatever is left over
+func presentCanvas(screen, canvas *ebiten.Image) {
+	scale, offsetX, offsetY := canvasTransform(screen.Bounds().Dx(), screen.Bounds().Dy())
+	op := &ebiten.DrawImageOptions{}
+	if scale == math.Floor(scale) {
+		op.Filter = ebiten.FilterNearest
+	} else {
+		op.Filter = ebiten.FilterLinear
+	}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(offsetX, offsetY)
+	screen.DrawImage(canvas, op)
+}
+
+// Mouse cursor position in world coordinates
+func (g *Game) cursorWorldPosition() Point {
+	cx, cy := ebiten.CursorPosition()
+	scale, offsetX, offsetY := canvasTransform(g.screenWidth, g.screenHeight)
+	return Point{
+		x: (float64(cx)-offsetX)/scale + g.camera.x,
+		y: (float64(cy)-offsetY)/scale + g.camera.y,
+	}
+}
+
+// F11 or Alt+Enter toggles fullscreen, F6 toggles integer scaling
+func (g *Game) handleDisplayKeys() {
+	d := &settings.Display
+	altEnter := inpututil.IsKeyJustPressed(ebiten.KeyEnter) && ebiten.IsKeyPressed(ebiten.KeyAlt)
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyF11) || altEnter:
+		toggleFullscreen()
+	case inpututil.IsKeyJustPressed(ebiten.KeyF6):
+		d.IntegerScale = !d.IntegerScale
+	default:
+		return
+	}
+	g.settingsChanged()
+}
+
+// Window modes, in the order the options menu cycles through them
+const (
+	windowed   = "windowed"
+	borderless = "borderless" // A window without decorations
+	fullscreen = "fullscreen"
+)
+
+var windowModes = []string{windowed, borderless, fullscreen}
+
+func toggleFullscreen() {
+	d := &settings.Display
+	if d.WindowMode == fullscreen {
+		d.WindowMode = windowed
+	} else {
+		d.WindowMode = fullscreen
+	}
+	applyWindowMode()
+}
+
+// Switch the window to the mode in the settings, remembering the window's
+// size when leaving a windowed mode
+func applyWindowMode() {
+	d := &settings.Display
+	if !ebiten.IsFullscreen() && d.WindowMode == fullscreen {
+		d.WindowWidth, d.WindowHeight = ebiten.WindowSize()
+	}
+	ebiten.SetWindowDecorated(d.WindowMode != borderless)
+	ebiten.SetFullscreen(d.WindowMode == fullscreen)
+}
+
+// Remember the window size for next time, then let the window close
+func (g *Game) closeWindow() error {
+	if !ebiten.IsFullscreen() {
+		settings.Display.WindowWidth, settings.Display.WindowHeight = ebiten.WindowSize()
+	}
+	if err := saveSettings(settings); err != nil {
+		log.Printf("failed to save settings: %v", err)
+	}
+	return ebiten.Termination
+}
@@ -8,6 +8,12 @@ import (
 )
 
 func (d *Dot) Draw(screen *ebiten.Image) {
+	if settings.Accessibility.HighContrastDots {
+		// Bigger dot with a dark outline so it stands out against any wall colour
+		ebitenutil.DrawCircle(screen, d.x, d.y, d.radius*2+2, color.Black)
+		ebitenutil.DrawCircle(screen, d.x, d.y, d.radius*2, d.color)
+		return
+	}
 	ebitenutil.DrawCircle(screen, d.x, d.y, d.radius, d.color)
 }
 
@@ -15,24 +21,26 @@ type Dot struct {
 	x, y   float64
 	radius float64
 	color  color.Color
+	power  bool // Power pellet
 }
 
-func generateDots() []Dot {
+const dotRadius = 3.0 // Size of dots
+
+func generateDots(walls []Wall, cage Square, width, height float64) []Dot {
 	dots := []Dot{}
 
-	spacing := 30.0 // Space between dots
-	radius := 3.0   // Size of dots
-	buffer := 10.0  // Minimum distance away from walls
+	spacing := 3.0 * tileSize // Space between dots
+	buffer := 1.0 * tileSize  // Minimum distance away from walls
 
-	for x := spacing; x <= screenWidth-spacing; x += spacing {
-		for y := spacing; y <= screenHeight-spacing; y += spacing {
+	for x := spacing; x <= width-spacing; x += spacing {
+		for y := spacing; y <= height-spacing; y += spacing {
 			// Check if point is in cage
-			inCage := x >= cageX && x <= cageX+cageWidth &&
-				y >= cageY && y <= cageY+cageHeight
+			inCage := x >= cage.Left.x && x <= cage.Right.x+cage.Right.Width &&
+				y >= cage.Top.y && y <= cage.Bottom.y+cage.Bottom.Height
 
 			// Check if point is near any wall, including cage walls with buffer distance
 
-			allWalls := append(Walls, Cage.Bottom, Cage.Left, Cage.Right, Cage.Top)
+			allWalls := append(walls[:len(walls):len(walls)], cage.Bottom, cage.Left, cage.Right, cage.Top)
 
 			nearWall := false
 			for _, wall := range allWalls {
@@ -48,7 +56,7 @@ func generateDots() []Dot {
 				dots = append(dots, Dot{
 					x:      x,
 					y:      y,
-					radius: radius,
+					radius: tunables.DotRadius,
 					color:  color.White,
 				})
 			}
@@ -59,7 +67,7 @@ func generateDots() []Dot {
 
 func generateTestDots() []Dot {
 	dots := []Dot{
-		{30, 30, 3, color.White},
+		{x: 3 * tileSize, y: 3 * tileSize, radius: tunables.DotRadius, color: color.White},
 	}
 	return dots
 }
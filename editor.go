@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+type editorTool int
+
+const (
+	toolWall editorTool = iota
+	toolDot
+	toolPellet
+	toolPacman
+	toolGhost
+	toolGhostExit
+	toolCage
+	toolTunnel
+)
+
+var editorToolNames = []string{"walls", "dots", "pellets", "pacman spawn", "ghost spawns", "ghost exit", "cage", "tunnels"}
+
+const (
+	defaultEditorPath = "levels/custom.json"
+	editorPanSpeed    = 240.0 // Pixels per second
+)
+
+// Editor paints a level on the tile grid. Walls, dots and pellets are kept
+// per tile and only turned back into a Level for play-testing and saving.
+type Editor struct {
+	level     Level  // Spawns, cage, tunnels and anything else not on a grid
+	source    *Level // The maze it was made from, so it's made again when that changes
+	walls     []bool
+	dots      []bool
+	pellets   []bool
+	tool      editorTool
+	path      string
+	nextGhost int // Ghost spawn replaced when all four are placed
+
+	dragging  bool
+	dragStart [2]int
+
+	message string
+}
+
+func newEditor(level *Level, path string) *Editor {
+	if path == "" {
+		path = defaultEditorPath
+	}
+	e := &Editor{level: *level.clone(), source: level, path: path}
+	tiles := level.Cols * level.Rows
+	e.walls = make([]bool, tiles)
+	e.dots = make([]bool, tiles)
+	e.pellets = make([]bool, tiles)
+
+	// A tile is a wall if its centre is inside one
+	walls := buildWalls(level.Walls)
+	for i := range e.walls {
+		c := e.centre(i).point()
+		for _, w := range walls {
+			if w.contains(c.x, c.y) {
+				e.walls[i] = true
+				break
+			}
+		}
+	}
+
+	dots := level.Dots
+	if len(dots) == 0 {
+		// Let the generated dots be edited like any others
+		w, h := level.size()
+		dots = Map(generateDots(walls, newCage(level.Cage.wall()), w, h), func(d Dot) TilePoint {
+			return TilePoint{Col: d.x / tileSize, Row: d.y / tileSize}
+		})
+	}
+	for _, p := range dots {
+		if i, ok := e.tileAt(p); ok {
+			e.dots[i] = true
+		}
+	}
+	for _, p := range level.Pellets {
+		if i, ok := e.tileAt(p); ok {
+			e.pellets[i] = true
+		}
+	}
+	return e
+}
+
+func (e *Editor) tileAt(p TilePoint) (int, bool) {
+	col, row := int(math.Floor(p.Col)), int(math.Floor(p.Row))
+	if col < 0 || row < 0 || col >= e.level.Cols || row >= e.level.Rows {
+		return 0, false
+	}
+	return row*e.level.Cols + col, true
+}
+
+func (e *Editor) centre(i int) TilePoint {
+	return TilePoint{Col: float64(i%e.level.Cols) + 0.5, Row: float64(i/e.level.Cols) + 0.5}
+}
+
+// Turn the grids back into a level
+func (e *Editor) toLevel() *Level {
+	level := e.level.clone()
+	level.Walls = mergeTiles(e.walls, e.level.Cols, e.level.Rows)
+	level.Dots = nil
+	level.Pellets = nil
+	for i := range e.dots {
+		if e.dots[i] {
+			level.Dots = append(level.Dots, e.centre(i))
+		}
+		if e.pellets[i] {
+			level.Pellets = append(level.Pellets, e.centre(i))
+		}
+	}
+	return level
+}
+
+// F1 switches between playing and editing the current maze
+func (g *Game) toggleEditor() {
+	g.editing = !g.editing
+	if g.editing && (g.editor == nil || g.editor.source != g.maze) {
+		g.editor = newEditor(g.maze, g.mazePath)
+	}
+	if g.editing {
+		g.sounds.stopChannel(channelSiren)
+	}
+}
+
+func (g *Game) updateEditor(dt float64) {
+	e := g.editor
+
+	for i := range editorToolNames {
+		if inpututil.IsKeyJustPressed(ebiten.Key1 + ebiten.Key(i)) {
+			e.tool = editorTool(i)
+			e.dragging = false
+		}
+	}
+
+	// Pan around mazes bigger than the screen
+	w, h := e.level.size()
+	if ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+		g.camera.x -= editorPanSpeed * dt
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+		g.camera.x += editorPanSpeed * dt
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+		g.camera.y -= editorPanSpeed * dt
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+		g.camera.y += editorPanSpeed * dt
+	}
+	g.camera.x, g.camera.y = clampView(g.camera.x, g.camera.y, w, h)
+
+	ctrl := ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta)
+	switch {
+	case ctrl && inpututil.IsKeyJustPressed(ebiten.KeyS):
+		if err := saveLevel(e.path, e.toLevel()); err != nil {
+			e.message = "save failed: " + err.Error()
+		} else {
+			e.message = "saved " + e.path
+		}
+		return
+	case inpututil.IsKeyJustPressed(ebiten.KeyV):
+		report := validateLevel(e.toLevel())
+		if problems := append(report.errors, report.warnings...); len(problems) > 0 {
+			e.message = strings.Join(problems, ", ")
+		} else {
+			e.message = "level ok"
+		}
+		return
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) && !ebiten.IsKeyPressed(ebiten.KeyAlt):
+		// Alt+Enter is fullscreen
+		g.playTest()
+		return
+	}
+
+	g.paint()
+}
+
+// Apply the current tool under the mouse
+func (g *Game) paint() {
+	e := g.editor
+	cursor := g.cursorWorldPosition()
+	tile := TilePoint{Col: cursor.x / tileSize, Row: cursor.y / tileSize}
+	i, ok := e.tileAt(tile)
+	if !ok {
+		return
+	}
+	col, row := i%e.level.Cols, i/e.level.Cols
+	centre := e.centre(i)
+	// Spawns snap to tile corners, where Pacman-sized bodies fit between walls
+	corner := TilePoint{Col: math.Round(tile.Col), Row: math.Round(tile.Row)}
+	add := ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft)
+	remove := ebiten.IsMouseButtonPressed(ebiten.MouseButtonRight)
+	clicked := inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
+
+	switch e.tool {
+	case toolWall, toolDot, toolPellet:
+		if !add && !remove {
+			return
+		}
+		grid := map[editorTool][]bool{toolWall: e.walls, toolDot: e.dots, toolPellet: e.pellets}[e.tool]
+		grid[i] = add
+		if e.tool == toolWall && add {
+			e.dots[i], e.pellets[i] = false, false
+		}
+	case toolPacman:
+		if clicked {
+			e.level.Pacman = corner
+		}
+	case toolGhostExit:
+		if clicked {
+			e.level.GhostExit = corner
+		}
+	case toolGhost:
+		switch {
+		case clicked && len(e.level.Ghosts) < len(Ghost):
+			e.level.Ghosts = append(e.level.Ghosts, corner)
+		case clicked:
+			e.level.Ghosts[e.nextGhost] = corner
+			e.nextGhost = (e.nextGhost + 1) % len(e.level.Ghosts)
+		case inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight):
+			for k, spawn := range e.level.Ghosts {
+				if math.Abs(spawn.Col-corner.Col) <= 1 && math.Abs(spawn.Row-corner.Row) <= 1 {
+					e.level.Ghosts = append(e.level.Ghosts[:k], e.level.Ghosts[k+1:]...)
+					e.nextGhost = 0
+					break
+				}
+			}
+		}
+	case toolCage, toolTunnel:
+		if clicked {
+			e.dragging = true
+			e.dragStart = [2]int{col, row}
+		}
+		if e.tool == toolTunnel && inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) {
+			kept := e.level.Tunnels[:0]
+			for _, t := range e.level.Tunnels {
+				if w := t.wall(); !w.contains(centre.point().x, centre.point().y) {
+					kept = append(kept, t)
+				}
+			}
+			e.level.Tunnels = kept
+		}
+		if e.dragging && inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
+			e.dragging = false
+			rect := e.dragRect(col, row)
+			if e.tool == toolCage {
+				e.level.Cage = rect
+			} else {
+				e.level.Tunnels = append(e.level.Tunnels, rect)
+			}
+		}
+	}
+}
+
+// Rectangle covering the drag start and the given tile
+func (e *Editor) dragRect(col, row int) TileRect {
+	minCol, maxCol := min(col, e.dragStart[0]), max(col, e.dragStart[0])
+	minRow, maxRow := min(row, e.dragStart[1]), max(row, e.dragStart[1])
+	return TileRect{Col: float64(minCol), Row: float64(minRow), Cols: float64(maxCol - minCol + 1), Rows: float64(maxRow - minRow + 1)}
+}
+
+// Start a fresh game on the edited level, if it passes the checks
+func (g *Game) playTest() {
+	level := g.editor.toLevel()
+	if problems := checkLevel(level); len(problems) > 0 {
+		g.editor.message = strings.Join(problems, ", ")
+		return
+	}
+	g.editing = false
+	g.mode = modeClassic
+	g.classicMaze = level
+	g.restart()
+	// Coming back carries on with the same edit
+	g.editor.source = g.maze
+}
+
+func (e *Editor) draw(screen *ebiten.Image) {
+	grid := color.RGBA{40, 40, 40, 255}
+	w, h := e.level.size()
+	for x := 0.0; x <= w; x += tileSize {
+		vector.StrokeLine(screen, float32(x), 0, float32(x), float32(h), 1, grid, false)
+	}
+	for y := 0.0; y <= h; y += tileSize {
+		vector.StrokeLine(screen, 0, float32(y), float32(w), float32(y), 1, grid, false)
+	}
+
+	palette := currentPalette()
+	for i := range e.walls {
+		c := e.centre(i).point()
+		x, y := float32(c.x-tileSize/2), float32(c.y-tileSize/2)
+		if e.walls[i] {
+			vector.DrawFilledRect(screen, x, y, tileSize, tileSize, palette.Walls[0], false)
+		}
+		if e.dots[i] {
+			ebitenutil.DrawCircle(screen, c.x, c.y, dotRadius, palette.Dot)
+		}
+		if e.pellets[i] {
+			ebitenutil.DrawCircle(screen, c.x, c.y, pelletRadius, palette.Dot)
+		}
+	}
+
+	outline := func(r TileRect, clr color.Color) {
+		w := r.wall()
+		vector.StrokeRect(screen, float32(w.x), float32(w.y), float32(w.Width), float32(w.Height), 2, clr, false)
+	}
+	outline(e.level.Cage, palette.Cage)
+	for _, t := range e.level.Tunnels {
+		outline(t, color.RGBA{0, 255, 255, 255})
+	}
+	for _, t := range e.level.Teleporters {
+		outline(t.A, color.White)
+		outline(t.B, color.White)
+	}
+
+	p := e.level.Pacman.point()
+	ebitenutil.DrawCircle(screen, p.x, p.y, pacmanRadius, palette.Pacman)
+	for i, spawn := range e.level.Ghosts {
+		p := spawn.point()
+		ebitenutil.DrawCircle(screen, p.x, p.y, pacmanRadius, palette.Ghosts[i%len(palette.Ghosts)])
+	}
+	p = e.level.GhostExit.point()
+	vector.StrokeCircle(screen, float32(p.x), float32(p.y), tileSize, 2, color.White, false)
+}
+
+func (e *Editor) drawHUD(screen *ebiten.Image) {
+	help := fmt.Sprintf("EDITOR  tool: %s  [1-8] tool  [V] check  [Enter] play  [Ctrl+S] save  [F1] back", editorToolNames[e.tool])
+	ebitenutil.DebugPrintAt(screen, help, 4, 2)
+	if e.message != "" {
+		ebitenutil.DebugPrintAt(screen, e.message, 4, screenHeight-18)
+	}
+}
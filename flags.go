@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// launchFlags set up the game from the command line, so a particular
+// scenario can be played without going through the menus
+type launchFlags struct {
+	level    int
+	seed     int64
+	maze     string
+	lives    int
+	god      bool
+	testDots bool
+	mode     string
+	scale    float64
+	virtual  bool   // Simulate audio timing instead of using the sound device
+	assets   string // Directory of files replacing the built-in assets
+	dev      bool   // Reload edited files while playing
+	tunables string // File of tunables for dev mode
+	skip     bool   // A scenario was asked for, so start playing straight away
+}
+
+// pacman [flags] [level.json]
+func parseLaunchFlags(args []string) launchFlags {
+	var f launchFlags
+	flags := flag.NewFlagSet("pacman", flag.ExitOnError)
+	flags.IntVar(&f.level, "level", 1, "level to start on")
+	flags.Int64Var(&f.seed, "seed", 0, "random seed, for the ghosts and endless mazes (default random)")
+	flags.StringVar(&f.maze, "maze", "", "level file to play")
+	flags.IntVar(&f.lives, "lives", 0, fmt.Sprintf("lives to start with, %d to %d (default from settings)", minLives, maxLives))
+	flags.BoolVar(&f.god, "god", false, "ghosts can't hurt pacman")
+	flags.BoolVar(&f.testDots, "test-dots", false, "use the test dot layout on levels without dots of their own")
+	flags.StringVar(&f.mode, "mode", modeClassic.String(), "game mode: "+strings.Join(gameModeNames, ", "))
+	flags.Float64Var(&f.scale, "scale", 0, "window size as a multiple of the playfield (default from settings)")
+	flags.BoolVar(&f.virtual, "virtual-audio", false, "keep time for sounds without playing them, for reproducible runs")
+	flags.StringVar(&f.assets, "assets", "", "directory of images, fonts and sounds to use instead of the built-in ones (default the assets directory in the config directory)")
+	flags.BoolVar(&f.dev, "dev", false, "reload the level, assets, asset pack and tunables as they're edited")
+	flags.StringVar(&f.tunables, "tunables", "", "tunables file for -dev, written with the defaults if missing (default "+tunablesFile+" in the config directory)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: pacman [flags] [level.json]")
+		fmt.Fprintln(flags.Output(), "       pacman validate|generate ...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if f.maze == "" {
+		f.maze = flags.Arg(0)
+	}
+	if f.seed == 0 {
+		f.seed = time.Now().UnixNano()
+	}
+	fail := func(format string, args ...any) {
+		fmt.Fprintf(flags.Output(), format+"\n", args...)
+		flags.Usage()
+		os.Exit(2)
+	}
+	switch {
+	case f.level < 1:
+		fail("-level must be at least 1")
+	case f.lives != 0 && (f.lives < minLives || f.lives > maxLives):
+		fail("-lives must be between %d and %d", minLives, maxLives)
+	case !slices.Contains(gameModeNames, f.mode):
+		fail("unknown -mode %q", f.mode)
+	case f.scale < 0:
+		fail("-scale can't be negative")
+	}
+	flags.Visit(func(fl *flag.Flag) {
+		if !slices.Contains([]string{"scale", "virtual-audio", "assets", "dev", "tunables"}, fl.Name) {
+			f.skip = true
+		}
+	})
+	f.skip = f.skip || f.maze != ""
+	return f
+}
+
+// Make the game the flags describe
+func (f launchFlags) newGame() (*Game, error) {
+	rng = rand.New(rand.NewSource(f.seed))
+	testDots = f.testDots
+	virtualAudio = f.virtual
+
+	// Tunables first, as everything made from here on uses them
+	var tunablesErr error
+	if devMode && tunablesPath != "" {
+		var t Tunables
+		t, tunablesErr = loadTunables(tunablesPath)
+		if tunablesErr != nil {
+			log.Printf("failed to load tunables, using defaults: %v", tunablesErr)
+		}
+		useTunables(t)
+	}
+
+	level := &defaultLevel
+	if f.maze != "" {
+		var err error
+		if level, err = loadLevel(f.maze); err != nil {
+			return nil, err
+		}
+	}
+	g := newGame(level)
+	g.mazePath = f.maze
+	g.mode = GameMode(slices.Index(gameModeNames, f.mode))
+	g.seed = f.seed
+	g.startLevel = f.level
+	g.startLives = f.lives
+	g.god = f.god
+	if tunablesErr != nil {
+		g.reloadErrors[tunablesPath] = tunablesErr
+	}
+	g.unranked = f.god || f.level != 1 || f.lives != 0 || f.testDots
+
+	if f.scale > 0 {
+		ebiten.SetWindowSize(int(screenWidth*f.scale), int(screenHeight*f.scale))
+	}
+	if f.skip {
+		g.start()
+	} else {
+		g.showTitle()
+	}
+	return g, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseLaunchFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want launchFlags
+	}{
+		{nil, launchFlags{level: 1, mode: "classic"}},
+		{[]string{"-level", "3", "-seed", "42", "-lives", "5", "-god", "-mode", "endless"},
+			launchFlags{level: 3, seed: 42, lives: 5, god: true, mode: "endless", skip: true}},
+		{[]string{"-seed", "7", "wide.json"}, launchFlags{level: 1, seed: 7, maze: "wide.json", mode: "classic", skip: true}},
+		{[]string{"-seed", "7", "-maze", "a.json", "b.json"}, launchFlags{level: 1, seed: 7, maze: "a.json", mode: "classic", skip: true}},
+		// Window and dev settings don't count as a scenario
+		{[]string{"-scale", "2", "-virtual-audio", "-dev"}, launchFlags{level: 1, mode: "classic", scale: 2, virtual: true, dev: true}},
+	}
+	for _, test := range tests {
+		got := parseLaunchFlags(test.args)
+		if test.want.seed == 0 {
+			if got.seed == 0 {
+				t.Errorf("%q: no random seed picked", test.args)
+			}
+			got.seed = 0
+		}
+		if got != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.args, got, test.want)
+		}
+	}
+}
+
+func TestCheatsAreUnranked(t *testing.T) {
+	settings = defaultSettings()
+	defer func() { testDots = false }()
+	tests := []struct {
+		args     []string
+		unranked bool
+	}{
+		{nil, false},
+		{[]string{"-seed", "1", "-mode", "endless"}, false},
+		{[]string{"-god"}, true},
+		{[]string{"-level", "2"}, true},
+		{[]string{"-lives", "5"}, true},
+		{[]string{"-test-dots"}, true},
+	}
+	for _, test := range tests {
+		g, err := parseLaunchFlags(append(slices.Clone(test.args), "-virtual-audio")).newGame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.unranked != test.unranked {
+			t.Errorf("%q: unranked %v, want %v", test.args, g.unranked, test.unranked)
+		}
+		if g.unranked {
+			scores := len(highScores)
+			g.points = 100
+			g.recordScore()
+			if len(highScores) != scores {
+				t.Errorf("%q: score recorded", test.args)
+			}
+		}
+	}
+}
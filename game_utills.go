@@ -1,7 +1,7 @@
 package main
 
 import (
-	"io/ioutil"
+	"io/fs"
 	"log"
 
 	"golang.org/x/image/font"
@@ -12,7 +12,7 @@ import (
 func generateGameFont() font.Face {
 	var face font.Face
 	fontFaceOnce.Do(func() {
-		tt, err := opentype.Parse(getFontBytes(retroFont))
+		tt, err := opentype.Parse(getFontBytes(assetPath(retroFont)))
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -28,17 +28,15 @@ func generateGameFont() font.Face {
 	return face
 }
 
-// Function to open TTF file and get bytes
+// Function to open TTF file from the assets and get bytes
 func getFontBytes(filePath string) []byte {
-	if fontBytes, ok := fontBytesCache.Get(filePath); ok {
-		// println("Cache hit")
+	if fontBytes, ok := fontBytesCache.Get(assetKey(filePath)); ok {
 		return fontBytes
 	}
-	println("Cache miss")
-	fontBytes, err := ioutil.ReadFile(filePath)
+	fontBytes, err := fs.ReadFile(assets, filePath)
 	if err != nil {
 		log.Fatalf("failed to read file: %v", err)
 	}
-	fontBytesCache.Add(filePath, fontBytes)
+	fontBytesCache.Add(assetKey(filePath), fontBytes)
 	return fontBytes
 }
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// Generated mazes are laid out on a lattice of junctions. Corridors between
+// junctions are wide enough for Pacman with a tile to spare, and a tile of
+// wall separates neighbouring corridors.
+const (
+	mazeCorridor   = 5 // Corridor width, in tiles
+	mazePitch      = mazeCorridor + 1
+	mazeLoopChance = 0.3 // Chance of each extra corridor on top of the spanning tree
+
+	// Junctions across and down for endless mode, fits on one screen
+	endlessNodesX = 10
+	endlessNodesY = 7
+)
+
+type mazeGenerator struct {
+	rng            *rand.Rand
+	nodesX, nodesY int
+	cols, rows     int
+	solid          []bool
+	edges          map[[2]int]bool // Pairs of connected junctions, lowest index first
+	cageX, cageY   int             // Top left junction of the 2×2 taken by the ghost house
+}
+
+// Generate a left-right symmetric maze from a seed. Every junction is
+// connected, there are no dead ends, and the ghost house sits in the middle
+// with a ring of corridor around it. nodesX must be even and at least 4,
+// nodesY at least 5 to leave a row for the tunnel.
+func generateMaze(seed int64, nodesX, nodesY int) *Level {
+	m := &mazeGenerator{
+		rng:    rand.New(rand.NewSource(seed)),
+		nodesX: nodesX,
+		nodesY: nodesY,
+		cols:   nodesX*mazePitch + 1,
+		rows:   nodesY*mazePitch + 1,
+		edges:  make(map[[2]int]bool),
+		cageX:  nodesX/2 - 1,
+		cageY:  nodesY/2 - 1,
+	}
+	m.spanningTree()
+	m.ringAroundCage()
+	m.addLoops()
+	m.removeDeadEnds()
+	return m.level(fmt.Sprintf("generated-%d", seed))
+}
+
+func (m *mazeGenerator) index(x, y int) int {
+	return y*m.nodesX + x
+}
+
+func (m *mazeGenerator) inCage(x, y int) bool {
+	return x >= m.cageX && x <= m.cageX+1 && y >= m.cageY && y <= m.cageY+1
+}
+
+// Junctions in the left half that can take a corridor
+func (m *mazeGenerator) usable(x, y int) bool {
+	return x >= 0 && y >= 0 && x < m.nodesX/2 && y < m.nodesY && !m.inCage(x, y)
+}
+
+// Connect two neighbouring junctions, and their mirror images
+func (m *mazeGenerator) connect(x1, y1, x2, y2 int) {
+	for _, mirror := range []bool{false, true} {
+		a, b := m.index(x1, y1), m.index(x2, y2)
+		if mirror {
+			a, b = m.index(m.nodesX-1-x1, y1), m.index(m.nodesX-1-x2, y2)
+		}
+		m.edges[[2]int{min(a, b), max(a, b)}] = true
+	}
+}
+
+func (m *mazeGenerator) connected(x1, y1, x2, y2 int) bool {
+	a, b := m.index(x1, y1), m.index(x2, y2)
+	return m.edges[[2]int{min(a, b), max(a, b)}]
+}
+
+// Neighbours a left half junction could connect to, including its mirror
+// image across the middle
+func (m *mazeGenerator) neighbours(x, y int) [][2]int {
+	var result [][2]int
+	for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+		nx, ny := x+d[0], y+d[1]
+		if m.usable(nx, ny) || (nx == m.nodesX/2 && ny >= 0 && ny < m.nodesY && !m.inCage(nx, ny)) {
+			result = append(result, [2]int{nx, ny})
+		}
+	}
+	return result
+}
+
+// Randomised depth first search over the left half
+func (m *mazeGenerator) spanningTree() {
+	visited := make(map[[2]int]bool)
+	stack := [][2]int{{0, 0}}
+	visited[stack[0]] = true
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		var options [][2]int
+		for _, n := range m.neighbours(current[0], current[1]) {
+			if m.usable(n[0], n[1]) && !visited[n] {
+				options = append(options, n)
+			}
+		}
+		if len(options) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		next := options[m.rng.Intn(len(options))]
+		m.connect(current[0], current[1], next[0], next[1])
+		visited[next] = true
+		stack = append(stack, next)
+	}
+}
+
+// Corridors all the way round the ghost house, joining both halves above
+// and below it, so the ghost exit and Pacman's spawn are always connected
+func (m *mazeGenerator) ringAroundCage() {
+	left, right := m.cageX-1, m.cageX+2
+	top, bottom := m.cageY-1, m.cageY+2
+	for x := left; x < right; x++ {
+		m.connect(x, top, x+1, top)
+		m.connect(x, bottom, x+1, bottom)
+	}
+	for y := top; y < bottom; y++ {
+		m.connect(left, y, left, y+1)
+	}
+}
+
+func (m *mazeGenerator) addLoops() {
+	for y := 0; y < m.nodesY; y++ {
+		for x := 0; x < m.nodesX/2; x++ {
+			if !m.usable(x, y) {
+				continue
+			}
+			for _, n := range m.neighbours(x, y) {
+				if m.rng.Float64() < mazeLoopChance {
+					m.connect(x, y, n[0], n[1])
+				}
+			}
+		}
+	}
+}
+
+func (m *mazeGenerator) removeDeadEnds() {
+	for y := 0; y < m.nodesY; y++ {
+		for x := 0; x < m.nodesX/2; x++ {
+			if !m.usable(x, y) {
+				continue
+			}
+			var open [][2]int
+			for _, n := range m.neighbours(x, y) {
+				if !m.connected(x, y, n[0], n[1]) {
+					open = append(open, n)
+				}
+			}
+			if len(m.neighbours(x, y))-len(open) == 1 && len(open) > 0 {
+				n := open[m.rng.Intn(len(open))]
+				m.connect(x, y, n[0], n[1])
+			}
+		}
+	}
+}
+
+// Top left tile of a junction's corridor
+func (m *mazeGenerator) tile(x, y int) (int, int) {
+	return 1 + x*mazePitch, 1 + y*mazePitch
+}
+
+// Clear the tiles from one junction's corridor to another's
+func (m *mazeGenerator) carve(x1, y1, x2, y2 int) {
+	c1, r1 := m.tile(x1, y1)
+	c2, r2 := m.tile(x2, y2)
+	for row := min(r1, r2); row < max(r1, r2)+mazeCorridor; row++ {
+		for col := min(c1, c2); col < max(c1, c2)+mazeCorridor; col++ {
+			m.solid[row*m.cols+col] = false
+		}
+	}
+}
+
+func (m *mazeGenerator) level(name string) *Level {
+	m.solid = make([]bool, m.cols*m.rows)
+	for i := range m.solid {
+		m.solid[i] = true
+	}
+
+	dots := make(map[TilePoint]bool)
+	lane := func(x, y int) TilePoint {
+		col, row := m.tile(x, y)
+		return TilePoint{Col: float64(col) + mazeCorridor/2.0, Row: float64(row) + mazeCorridor/2.0}
+	}
+	for key := range m.edges {
+		x1, y1, x2, y2 := key[0]%m.nodesX, key[0]/m.nodesX, key[1]%m.nodesX, key[1]/m.nodesX
+		m.carve(x1, y1, x2, y2)
+		// Dots every three tiles down the middle of the corridor
+		a, b := lane(x1, y1), lane(x2, y2)
+		for step := 0.0; step <= mazePitch; step += 3 {
+			t := step / mazePitch
+			dots[TilePoint{Col: a.Col + (b.Col-a.Col)*t, Row: a.Row + (b.Row-a.Row)*t}] = true
+		}
+	}
+	m.carve(m.cageX, m.cageY, m.cageX+1, m.cageY+1)
+
+	// A tunnel through the outer wall on a row away from the ghost house
+	var rows []int
+	for y := 0; y < m.nodesY; y++ {
+		if y < m.cageY-1 || y > m.cageY+2 {
+			rows = append(rows, y)
+		}
+	}
+	tunnelY := rows[m.rng.Intn(len(rows))]
+	_, tunnelRow := m.tile(0, tunnelY)
+	for row := tunnelRow; row < tunnelRow+mazeCorridor; row++ {
+		m.solid[row*m.cols] = false
+		m.solid[row*m.cols+m.cols-1] = false
+	}
+
+	cageCol, cageRow := m.tile(m.cageX, m.cageY)
+	cageSize := float64(mazePitch + mazeCorridor)
+	middle := float64(cageCol) + cageSize/2
+	level := &Level{
+		Name:   name,
+		Cols:   m.cols,
+		Rows:   m.rows,
+		Walls:  mergeTiles(m.solid, m.cols, m.rows),
+		Cage:   TileRect{Col: float64(cageCol), Row: float64(cageRow), Cols: cageSize, Rows: cageSize},
+		Pacman: TilePoint{Col: middle, Row: lane(0, m.cageY+2).Row},
+		Ghosts: []TilePoint{
+			{Col: float64(cageCol) + 3, Row: float64(cageRow) + 3},
+			{Col: float64(cageCol) + cageSize - 3, Row: float64(cageRow) + 3},
+			{Col: float64(cageCol) + 3, Row: float64(cageRow) + cageSize - 3},
+			{Col: float64(cageCol) + cageSize - 3, Row: float64(cageRow) + cageSize - 3},
+		},
+		GhostExit: TilePoint{Col: middle, Row: lane(0, m.cageY-1).Row},
+		Tunnels: []TileRect{
+			{Col: 0, Row: float64(tunnelRow), Cols: 3, Rows: mazeCorridor},
+			{Col: float64(m.cols - 3), Row: float64(tunnelRow), Cols: 3, Rows: mazeCorridor},
+		},
+	}
+
+	// Power pellets in the corners instead of dots
+	for _, corner := range [][2]int{{0, 0}, {m.nodesX - 1, 0}, {0, m.nodesY - 1}, {m.nodesX - 1, m.nodesY - 1}} {
+		p := lane(corner[0], corner[1])
+		delete(dots, p)
+		level.Pellets = append(level.Pellets, p)
+	}
+	// Walk the grid so the output doesn't depend on map order
+	for row := 0; row < m.rows; row++ {
+		for col := 0; col < m.cols; col++ {
+			if p := (TilePoint{Col: float64(col) + 0.5, Row: float64(row) + 0.5}); dots[p] {
+				level.Dots = append(level.Dots, p)
+			}
+		}
+	}
+	return level
+}
+
+// pacman generate [-seed n] [-width n] [-height n] [-o level.json]
+func runGenerate(args []string) int {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	seed := flags.Int64("seed", time.Now().UnixNano(), "random seed")
+	width := flags.Int("width", endlessNodesX, "junctions across, even and at least 4")
+	height := flags.Int("height", endlessNodesY, "junctions down, at least 5")
+	output := flags.String("o", "", "level file to write, standard output if empty")
+	flags.Parse(args)
+	if *width < 4 || *width%2 != 0 || *height < 5 {
+		fmt.Fprintln(os.Stderr, "width must be even and at least 4, height at least 5")
+		return 2
+	}
+
+	level := generateMaze(*seed, *width, *height)
+	if *output != "" {
+		if err := saveLevel(*output, level); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	data, err := json.MarshalIndent(level, "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(string(data))
+	return 0
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerateMazeFromSeed(t *testing.T) {
+	a := generateMaze(42, endlessNodesX, endlessNodesY)
+	b := generateMaze(42, endlessNodesX, endlessNodesY)
+	if !reflect.DeepEqual(a, b) {
+		t.Error("the same seed made different mazes")
+	}
+	if c := generateMaze(43, endlessNodesX, endlessNodesY); reflect.DeepEqual(a.Walls, c.Walls) {
+		t.Error("different seeds made the same maze")
+	}
+}
+
+func TestGeneratedMazesAreValid(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		level := generateMaze(seed, endlessNodesX, endlessNodesY)
+		if report := validateLevel(level); len(report.errors) > 0 {
+			t.Errorf("seed %d: %v", seed, report.errors)
+		}
+		if len(level.Dots) == 0 || len(level.Tunnels) == 0 {
+			t.Errorf("seed %d: %d dots and %d tunnels", seed, len(level.Dots), len(level.Tunnels))
+		}
+	}
+}
+
+func TestGeneratedMazesAreSymmetric(t *testing.T) {
+	level := generateMaze(7, endlessNodesX, endlessNodesY)
+	walls := make(map[TileRect]bool)
+	for _, w := range level.Walls {
+		walls[w] = true
+	}
+	for _, w := range level.Walls {
+		mirror := TileRect{Col: float64(level.Cols) - w.Col - w.Cols, Row: w.Row, Cols: w.Cols, Rows: w.Rows}
+		if !walls[mirror] {
+			t.Fatalf("wall %v has no mirror image", w)
+		}
+	}
+}
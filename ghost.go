@@ -1,8 +1,21 @@
 package main
 
+// Ghost templates, one per personality. Positions come from the level,
+// speeds are in pixels per second.
 var Ghost = [4]Pacman{
-	{x: cageX + 50, y: cageY + 50, radius: pacmanRadius, angle: 0, color: lightBlue, speed: 1, variety: CHASER},
-	{x: cageX + 100, y: cageY + 50, radius: pacmanRadius, angle: 0, color: red, speed: 1, variety: AMBUSH},
-	{x: cageX + 150, y: cageY + 50, radius: pacmanRadius, angle: 0, color: green, speed: 1, variety: PATROL},
-	{x: cageX + 100, y: cageY + 100, radius: pacmanRadius, angle: 0, color: orange, speed: 1, variety: RANDOM},
+	{radius: pacmanRadius, angle: 0, color: lightBlue, speed: ghostSpeed, variety: CHASER},
+	{radius: pacmanRadius, angle: 0, color: red, speed: ghostSpeed, variety: AMBUSH},
+	{radius: pacmanRadius, angle: 0, color: green, speed: ghostSpeed, variety: PATROL},
+	{radius: pacmanRadius, angle: 0, color: orange, speed: ghostSpeed, variety: RANDOM},
+}
+
+// How much faster the ghosts get on reaching a level
+func levelSpeedUp(level int) float64 {
+	switch {
+	case level == 5 || level == 10 || level == 15:
+		return 30
+	case level >= 20:
+		return 7.5
+	}
+	return 0
 }
@@ -3,7 +3,6 @@ package main
 import (
 	"image/color"
 	"math"
-	"math/rand"
 )
 
 type Point struct {
@@ -64,38 +63,27 @@ type Pacman struct {
 	speed   float64
 	lastDir Point // Store last movement direction to prevent zigzagging
 	color   color.Color
+	onPad   bool // Standing on a teleporter pad
 }
 
 // Find best available direction towards a target that doesn't hit walls
 func (g *Game) findBestDirection(ghost *Pacman, target Point) Point {
 	bestDir := Point{x: 0, y: 0}
 	bestScore := math.Inf(-1)
+	stepSize := ghost.speed * simStep
 	currentDist := distance(ghost.x, ghost.y, target.x, target.y)
 
 	// Check each possible direction
 	for _, dir := range directions {
 		// Check a few steps ahead for wall collisions
-		const lookAhead = 3
-		validPath := true
-		newX, newY := ghost.x, ghost.y
-
-		for step := 1; step <= lookAhead; step++ {
-			checkX := newX + dir.x*ghost.speed*float64(step)
-			checkY := newY + dir.y*ghost.speed*float64(step)
-
-			if g.anyCollision(checkX, checkY) {
-				validPath = false
-				break
-			}
-		}
-
-		if !validPath {
+		reach := stepSize * tunables.LookAhead
+		if g.sweptCollision(ghost.x, ghost.y, ghost.x+dir.x*reach, ghost.y+dir.y*reach, ghost.radius) {
 			continue
 		}
 
 		// Calculate how good this direction is
-		newX = ghost.x + dir.x*ghost.speed
-		newY = ghost.y + dir.y*ghost.speed
+		newX := ghost.x + dir.x*stepSize
+		newY := ghost.y + dir.y*stepSize
 		newDist := distance(newX, newY, target.x, target.y)
 
 		// Score based on distance improvement and direction consistency
@@ -115,10 +103,10 @@ func (g *Game) findBestDirection(ghost *Pacman, target Point) Point {
 	// If no valid direction found, try to find any valid direction
 	if bestScore == math.Inf(-1) {
 		for _, dir := range directions {
-			newX := ghost.x + dir.x*ghost.speed
-			newY := ghost.y + dir.y*ghost.speed
+			newX := ghost.x + dir.x*stepSize
+			newY := ghost.y + dir.y*stepSize
 
-			if !g.collidesWithWall(newX, newY) {
+			if !g.collidesWithWall(newX, newY, ghost.radius) {
 				return dir
 			}
 		}
@@ -134,13 +122,23 @@ func (g *Game) ghostAi(pacmen []Pacman) {
 
 		// Normal movement logic for ghosts outside cage
 		target := g.getGhostTarget(p, Point{x: g.pacman.x, y: g.pacman.y})
+		target = g.nextWaypoint(p, target)
+		if g.ruleset().TileLocked {
+			g.moveGhostOnLanes(p, target)
+			g.followLinks(p)
+			continue
+		}
 		bestDir := g.findBestDirection(p, target)
 
-		newX := p.x + bestDir.x*p.speed
-		newY := p.y + bestDir.y*p.speed
+		speed := p.speed * simStep * currentDifficulty().GhostSpeed
+		if g.inTunnel(p.x, p.y) {
+			speed *= tunnelSpeedFactor
+		}
+		newX := p.x + bestDir.x*speed
+		newY := p.y + bestDir.y*speed
 
 		// Collision avoidance with other ghosts
-		const minSeparation = 30.0
+		minSeparation := tunables.MinSeparation
 		for j := range pacmen {
 			if i != j {
 				other := &pacmen[j]
@@ -152,7 +150,7 @@ func (g *Game) ghostAi(pacmen []Pacman) {
 					proposedX := newX + repulsionX*strength*2
 					proposedY := newY + repulsionY*strength*2
 
-					if !g.anyCollision(proposedX, proposedY) {
+					if !g.sweptCollision(p.x, p.y, proposedX, proposedY, p.radius) {
 						newX = proposedX
 						newY = proposedY
 					}
@@ -160,12 +158,41 @@ func (g *Game) ghostAi(pacmen []Pacman) {
 			}
 		}
 
-		if !g.anyCollision(newX, newY) {
+		if !g.sweptCollision(p.x, p.y, newX, newY, p.radius) && g.inBounds(newX, newY, p.radius) {
 			p.x = newX
 			p.y = newY
 			p.lastDir = bestDir
+			g.followLinks(p)
+		}
+	}
+}
+
+// Where to steer for on the way to target. Follows the maze, so ghosts take
+// tunnels and teleporters when they are the shorter way round.
+func (g *Game) nextWaypoint(ghost *Pacman, target Point) Point {
+	const waypointTiles = 4
+	from, ok := g.nav.nearestOpen(ghost.x, ghost.y)
+	if !ok {
+		return target
+	}
+	to, ok := g.nav.nearestOpen(target.x, target.y)
+	if !ok {
+		return target
+	}
+
+	current := from
+	for range waypointTiles {
+		next, ok := g.nav.next(current, to)
+		if !ok {
+			// Within reach of the target, or no way through
+			return target
+		}
+		if g.nav.isLink(current, next) {
+			return g.nav.linkEntrance(current, next)
 		}
+		current = next
 	}
+	return g.nav.centre(current)
 }
 
 // Calculate target position based on ghost personality
@@ -173,7 +200,7 @@ func (g *Game) getGhostTarget(ghost *Pacman, player Point) Point {
 	switch ghost.variety {
 	case CHASER:
 		// Directly chase the player if path available, otherwise target nearby accessible position
-		if !g.collidesWithWall(player.x, player.y) {
+		if !g.collidesWithWall(player.x, player.y, ghost.radius) {
 			return player
 		}
 		// Find closest accessible point to player
@@ -184,7 +211,7 @@ func (g *Game) getGhostTarget(ghost *Pacman, player Point) Point {
 			checkX := player.x + dir.x*50
 			checkY := player.y + dir.y*50
 
-			if !g.collidesWithWall(checkX, checkY) {
+			if !g.collidesWithWall(checkX, checkY, ghost.radius) {
 				dist := distance(ghost.x, ghost.y, checkX, checkY)
 				if dist < bestDist {
 					bestDist = dist
@@ -205,7 +232,7 @@ func (g *Game) getGhostTarget(ghost *Pacman, player Point) Point {
 		for d := ambushDistance; d > 0; d -= 10 {
 			checkX := player.x + playerDir.x*d
 			checkY := player.y + playerDir.y*d
-			if !g.collidesWithWall(checkX, checkY) {
+			if !g.collidesWithWall(checkX, checkY, ghost.radius) {
 				return Point{x: checkX, y: checkY}
 			}
 		}
@@ -218,21 +245,21 @@ func (g *Game) getGhostTarget(ghost *Pacman, player Point) Point {
 			midX := (player.x + ghost.scatter.x) / 2
 			midY := (player.y + ghost.scatter.y) / 2
 
-			if !g.collidesWithWall(midX, midY) {
+			if !g.collidesWithWall(midX, midY, ghost.radius) {
 				return Point{x: midX, y: midY}
 			}
 		}
 		return player
 
 	case RANDOM:
-		if rand.Float64() < 0.02 {
+		if rng.Float64() < 0.02 {
 			// Find random valid position
 			for attempts := 0; attempts < 10; attempts++ {
-				angle := rand.Float64() * 2 * math.Pi
+				angle := rng.Float64() * 2 * math.Pi
 				targetX := ghost.x + math.Cos(angle)*100
 				targetY := ghost.y + math.Sin(angle)*100
 
-				if !g.collidesWithWall(targetX, targetY) {
+				if !g.collidesWithWall(targetX, targetY, ghost.radius) {
 					return Point{x: targetX, y: targetY}
 				}
 			}
@@ -2,18 +2,21 @@ package main
 
 import (
 	"image/color"
+	"math/rand"
 	"sync"
+	"time"
 
-	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2"
 	"golang.org/x/image/font"
 )
 
+// Asset paths are relative to the assets directory, see assets.go
 const (
 	assetsDir = "assets"
-	imageDir  = assetsDir + "/img/"
-	fontDir   = assetsDir + "/font/"
-	audioDir  = assetsDir + "/audio/"
-	retroFont = fontDir + "/retro.ttf"
+	imageDir  = "img/"
+	fontDir   = "font/"
+	audioDir  = "audio/"
+	retroFont = fontDir + "retro.ttf"
 )
 const (
 	Up = 1 << iota
@@ -26,20 +29,23 @@ var game *Game
 
 var Dots []Dot
 
-const dev = true
+// Source of everything random in play, seeded from the -seed flag so a run
+// can be repeated
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
 
-var Cage = Square{
-	Top:    Wall{x: cageX, y: cageY, Width: cageWidth, Height: wallThickness, Color: blue},
-	Right:  Wall{x: cageX + cageWidth - wallThickness, y: cageY, Width: wallThickness, Height: cageHeight, Color: blue},
-	Bottom: Wall{x: cageX, y: cageY + cageHeight - wallThickness, Width: cageWidth, Height: wallThickness, Color: blue},
-	Left:   Wall{x: cageX, y: cageY, Width: wallThickness, Height: cageHeight, Color: blue},
-}
+// Use the test dot layout on levels that don't list their own dots
+var testDots bool
+
+const sampleRate = 44100
 
+// The world is laid out on a grid of tiles, tileSize logical pixels across.
+// Everything is drawn at this logical size and scaled to fit the window.
 const (
-	sampleRate           = 44100
-	screenWidth          = 640
-	screenHeight         = 480
-	backgroundSampleRate = sampleRate / 2
+	tileSize     = 10
+	worldCols    = 64
+	worldRows    = 48
+	screenWidth  = worldCols * tileSize
+	screenHeight = worldRows * tileSize
 )
 
 var (
@@ -51,31 +57,27 @@ var (
 	orange    = color.RGBA{255, 153, 0, 255}
 )
 
+// Ghost house position and size, in tiles
 const (
-	wallThickness float64 = 12
-	cageWidth     float64 = 200
-	cageHeight    float64 = 150
-	pacmanRadius  float64 = 20
-	pacmanOffsetY float64 = WallMinimumOffset * 2
+	cageCols = 20
+	cageRows = 16
+	cageCol  = (worldCols - cageCols) / 2
+	cageRow  = (worldRows - cageRows) / 2
 )
 
-var (
-	pacmanPos = Point{
-		x: cageX + cageWidth/2,
-		y: cageY + cageHeight + pacmanOffsetY,
-	}
+const (
+	wallThickness float64 = 1 * tileSize
+	pacmanRadius  float64 = 2 * tileSize
+	pacmanSpeed   float64 = 60 // Pixels per second
+	ghostSpeed    float64 = 30
 )
 
-var cageX = (screenWidth - cageWidth) / 2
-var cageY = (screenHeight - cageHeight) / 2
-
 type Direction int
 
 const (
 	None              Direction = iota
-	WallMinimumOffset           = 60
-	WallWidth                   = 100
-	lives                       = 3
+	WallMinimumOffset           = 6 * tileSize
+	WallWidth                   = 10 * tileSize
 )
 
 // Cache for font face
@@ -94,8 +96,6 @@ var (
 type Game struct {
 	pacman            Pacman
 	cage              Square
-	mainPlayer        *audio.Player
-	mainContext       *audio.Context
 	ghost             []Pacman
 	direction         Direction
 	walls             []Wall
@@ -103,10 +103,47 @@ type Game struct {
 	wallSize          float64
 	gameOverState     bool
 	livesLeft         int
-	backgroundPlayer  *audio.Player
-	backgroundContext *audio.Context
 	points            int
-	audioPlayers      map[string]*audio.Player
-	audioMux          sync.RWMutex
+	sounds            *soundManager
+	cues              []*ghostCue // Ghost audio cues, made when first needed
+	afterJingle       func()      // Carries on once the jingle playing has finished
 	level             int
+	canvas            *ebiten.Image
+	world             *ebiten.Image
+	maze              *Level
+	worldWidth        float64
+	worldHeight       float64
+	spawn             Point
+	camera            Camera
+	showMinimap       bool
+	tunnels           []Wall
+	teleporters       []teleporter
+	nav               *navGrid
+	grid              *spatialGrid // Walls and dots by where they are
+	dotsAtStart       int          // How many dots the level started with
+	ghostExit         Point
+	reloadErrors      map[string]error // Dev mode reloads that failed, by the path reloaded
+	screenWidth       int              // Size of the window in device pixels, from Layout
+	screenHeight      int
+	editor            *Editor
+	editing           bool
+	mazePath          string // Level file the maze was loaded from, if any
+	mode              GameMode
+	seed              int64 // Endless mode mazes are generated from this plus the level number
+	startLevel        int   // Level a new game starts on
+	startLives        int   // Lives a new game starts with, settings.Gameplay.Lives if 0
+	god               bool  // Ghosts can't hurt Pacman
+	unranked          bool  // Started with a cheat or a head start, so scores aren't recorded
+	lastFrame         time.Time
+	accumulator       float64  // Seconds not yet simulated
+	previous          snapshot // Positions before the last step, for drawing in between
+	menus             []*menu  // Open menus, the one on top taking input
+	onTitle           bool
+	quitting          bool
+	classicMaze       *Level  // The maze classic mode plays on
+	idleTime          float64 // Seconds on the title screen without input
+	demo              bool    // The attract loop is playing
+	demoTime          float64
+	gameOverTime      float64   // Seconds since the game ended
+	rebinding         *[]string // Keys of the action waiting for a key press to bind
 }
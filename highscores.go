@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	highScoresFile = "highscores.json"
+	maxHighScores  = 10
+)
+
+// HighScore is one finished game on the high score table
+type HighScore struct {
+	Score int    `json:"score"`
+	Level int    `json:"level"`
+	Mode  string `json:"mode"`
+	Date  string `json:"date"`
+}
+
+var highScores []HighScore
+
+func loadHighScores() ([]HighScore, error) {
+	dir, err := configDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, highScoresFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var scores []HighScore
+	if err := json.Unmarshal(data, &scores); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+func saveHighScores(scores []HighScore) error {
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(scores, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, highScoresFile), data, 0o644)
+}
+
+// The table with score added, best first, trimmed to maxHighScores
+func addHighScore(scores []HighScore, score HighScore) []HighScore {
+	scores = append(scores, score)
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Score > scores[j].Score })
+	return scores[:min(len(scores), maxHighScores)]
+}
+
+// Put the game just finished on the table, if it scored anything
+func (g *Game) recordScore() {
+	if g.points == 0 || g.demo || g.unranked {
+		return
+	}
+	highScores = addHighScore(highScores, HighScore{
+		Score: g.points,
+		Level: g.level,
+		Mode:  g.mode.String(),
+		Date:  time.Now().Format("2006-01-02"),
+	})
+	if err := saveHighScores(highScores); err != nil {
+		log.Printf("failed to save high scores: %v", err)
+	}
+}
+
+func (g *Game) highScoresMenu() *menu {
+	m := &menu{title: "HIGH SCORES", back: g.closeMenu}
+	for i, s := range highScores {
+		m.items = append(m.items, menuItem{label: fmt.Sprintf("%2d. %6d  L%d %s", i+1, s.Score, s.Level, s.Mode)})
+	}
+	if len(highScores) == 0 {
+		m.items = append(m.items, menuItem{label: "No scores yet"})
+	}
+	m.items = append(m.items, menuItem{label: "Back", activate: g.closeMenu})
+	return m
+}
@@ -1,45 +1,42 @@
 package main
 
-import "github.com/hajimehoshi/ebiten/v2/audio"
+import "log"
 
 func init() {
-	// Initialize dots
-	// If dev is true, generate test dots
-	// Otherwise, create dots
-	if dev {
-		Dots = generateTestDots()
-	} else {
-		Dots = generateDots()
+	var err error
+	// Packs first, so the settings can choose one
+	findPacks()
+	settings, err = loadSettings()
+	if err != nil {
+		log.Printf("failed to load settings, using defaults: %v", err)
 	}
-	fontFace = generateGameFont()
-	audioContext := audio.NewContext(sampleRate)
-	game = &Game{
+	highScores, err = loadHighScores()
+	if err != nil {
+		log.Printf("failed to load high scores: %v", err)
+	}
+}
+
+// Set up a new game on the given maze, ready to start from the title screen
+func newGame(level *Level) *Game {
+	g := &Game{
 		pacman: Pacman{
-			x:      pacmanPos.x,
-			y:      pacmanPos.y,
-			radius: pacmanRadius,
+			radius: tunables.PacmanRadius,
 			angle:  0,
 			color:  yellow,
 		},
-		cage:              Cage,
-		mainContext:       audioContext,
-		ghost:             append([]Pacman{}, Ghost[:]...),
 		direction:         None,
-		walls:             Walls[:],
 		introMusicPlaying: true,
 		wallSize:          wallThickness,
-		livesLeft:         lives,
-		backgroundContext: audioContext,
-		audioPlayers:      make(map[string]*audio.Player),
+		livesLeft:         settings.Gameplay.Lives,
+		sounds:            newSoundManager(newAudioBackend()),
 		points:            0,
 		level:             1,
+		startLevel:        1,
+		showMinimap:       true,
+		reloadErrors:      make(map[string]error),
 	}
-
-	// Initialize intro music
-	// a
-	player, err := game.getAudioPlayer(audioDir + "intro.wav")
-	if err == nil {
-		game.mainPlayer = player
-		player.Play()
-	}
+	// Dots are placed by the level, or the test dots with -test-dots
+	g.classicMaze = level
+	g.useLevel(level)
+	return g
 }
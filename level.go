@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+)
+
+// TileRect is a rectangle on the tile grid
+type TileRect struct {
+	Col  float64 `json:"col"`
+	Row  float64 `json:"row"`
+	Cols float64 `json:"cols"`
+	Rows float64 `json:"rows"`
+}
+
+// TilePoint is a position on the tile grid, fractions of a tile allowed
+type TilePoint struct {
+	Col float64 `json:"col"`
+	Row float64 `json:"row"`
+}
+
+// Level is a maze as stored in a level file. Everything is in tiles.
+type Level struct {
+	Name   string      `json:"name"`
+	Cols   int         `json:"cols"`
+	Rows   int         `json:"rows"`
+	Walls  []TileRect  `json:"walls"`
+	Cage   TileRect    `json:"cage"`
+	Pacman TilePoint   `json:"pacman"`
+	Ghosts []TilePoint `json:"ghosts"` // One per ghost personality, in order
+	Dots   []TilePoint `json:"dots"`   // Generated from the walls when empty
+
+	Pellets   []TilePoint `json:"pellets"`
+	GhostExit TilePoint   `json:"ghostExit"` // Where ghosts leave the house
+
+	// Openings on the maze edge; leaving through one wraps to the opposite edge,
+	// which needs a tunnel of its own
+	Tunnels     []TileRect   `json:"tunnels"`
+	Teleporters []Teleporter `json:"teleporters"`
+}
+
+var defaultLevel = Level{
+	Name:      "default",
+	Cols:      worldCols,
+	Rows:      worldRows,
+	Walls:     defaultWalls,
+	Cage:      TileRect{Col: cageCol, Row: cageRow, Cols: cageCols, Rows: cageRows},
+	Pacman:    TilePoint{Col: cageCol + cageCols/2, Row: cageRow + cageRows + 2*wallOffset},
+	GhostExit: TilePoint{Col: cageCol + cageCols/2, Row: cageRow - wallOffset/2},
+	Ghosts: []TilePoint{
+		{Col: cageCol + 5, Row: cageRow + 5},
+		{Col: cageCol + 10, Row: cageRow + 5},
+		{Col: cageCol + 15, Row: cageRow + 5},
+		{Col: cageCol + 10, Row: cageRow + 10},
+	},
+	Pellets: []TilePoint{
+		{Col: 3, Row: 3},
+		{Col: worldCols - 3, Row: 3},
+		{Col: 3, Row: worldRows - 3},
+		{Col: worldCols - 3, Row: worldRows - 3},
+	},
+}
+
+func (r TileRect) wall() Wall {
+	return tileWall(r.Col, r.Row, r.Cols, r.Rows)
+}
+
+func (p TilePoint) point() Point {
+	return Point{x: p.Col * tileSize, y: p.Row * tileSize}
+}
+
+// Size of the level in logical pixels
+func (l *Level) size() (float64, float64) {
+	return float64(l.Cols) * tileSize, float64(l.Rows) * tileSize
+}
+
+func loadLevel(path string) (*Level, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read level file: %w", err)
+	}
+	level := &Level{}
+	if err := json.Unmarshal(data, level); err != nil {
+		return nil, fmt.Errorf("failed to parse level file: %w", err)
+	}
+	if level.Cols <= 0 || level.Rows <= 0 {
+		return nil, errors.New("level must have a positive size")
+	}
+	if len(level.Ghosts) == 0 {
+		return nil, errors.New("level has no ghost spawns")
+	}
+	if level.GhostExit == (TilePoint{}) {
+		// Older levels: just above the middle of the cage
+		level.GhostExit = TilePoint{Col: level.Cage.Col + level.Cage.Cols/2, Row: level.Cage.Row - 3}
+	}
+	return level, nil
+}
+
+// Copy of the level that shares none of its slices
+func (l *Level) clone() *Level {
+	c := *l
+	c.Walls = append([]TileRect(nil), l.Walls...)
+	c.Ghosts = append([]TilePoint(nil), l.Ghosts...)
+	c.Dots = append([]TilePoint(nil), l.Dots...)
+	c.Pellets = append([]TilePoint(nil), l.Pellets...)
+	c.Tunnels = append([]TileRect(nil), l.Tunnels...)
+	c.Teleporters = append([]Teleporter(nil), l.Teleporters...)
+	return &c
+}
+
+func saveLevel(path string, level *Level) error {
+	data, err := json.MarshalIndent(level, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Set up the walls and everything else solid from the level,
+// without touching Pacman, the ghosts or the dots
+func (g *Game) loadMaze(level *Level) {
+	g.maze = level
+	g.worldWidth, g.worldHeight = level.size()
+	g.walls = buildWalls(level.Walls)
+	g.cage = newCage(level.Cage.wall())
+	g.spawn = level.Pacman.point()
+	g.ghostExit = level.GhostExit.point()
+	g.tunnels = Map(level.Tunnels, TileRect.wall)
+	g.teleporters = Map(level.Teleporters, func(t Teleporter) teleporter {
+		return teleporter{a: t.A.wall(), b: t.B.wall()}
+	})
+	g.grid = newSpatialGrid(g.worldWidth, g.worldHeight, g.walls)
+	g.nav = g.buildNavGrid(tileSize)
+}
+
+// Replace the maze, putting Pacman and the ghosts back on their spawns
+func (g *Game) useLevel(level *Level) {
+	g.loadMaze(level)
+
+	g.ghost = g.ghost[:0]
+	for i, spawn := range level.Ghosts {
+		ghost := Ghost[i%len(Ghost)]
+		ghost.x, ghost.y = spawn.point().x, spawn.point().y
+		g.ghost = append(g.ghost, ghost)
+	}
+
+	g.resetDots()
+	g.pacman.x, g.pacman.y = g.spawn.x, g.spawn.y
+	g.camera.Snap(g.spawn, g.worldWidth, g.worldHeight)
+	g.applyPalette()
+}
+
+// Put every dot back for a new level. Levels listing their own dots always
+// get them, otherwise -test-dots swaps in the test dots.
+func (g *Game) resetDots() {
+	if testDots && len(g.maze.Dots) == 0 {
+		Dots = append(generateTestDots(), g.pellets()...)
+	} else {
+		Dots = g.levelDots()
+	}
+	g.dotsAtStart = len(Dots)
+	g.grid.indexDots(Dots)
+}
+
+// The dots and pellets the level starts with
+func (g *Game) levelDots() []Dot {
+	dots := Map(g.maze.Dots, func(p TilePoint) Dot {
+		return Dot{x: p.point().x, y: p.point().y, radius: tunables.DotRadius, color: color.White}
+	})
+	if len(dots) == 0 {
+		dots = generateDots(g.walls, g.cage, g.worldWidth, g.worldHeight)
+	}
+	return append(dots, g.pellets()...)
+}
+
+func (g *Game) pellets() []Dot {
+	return Map(g.maze.Pellets, func(p TilePoint) Dot {
+		return Dot{x: p.point().x, y: p.point().y, radius: tunables.PelletRadius, color: color.White, power: true}
+	})
+}
+
+// Cover the set tiles of a cols×rows grid with as few rectangles as we easily can
+func mergeTiles(tiles []bool, cols, rows int) []TileRect {
+	var rects []TileRect
+	used := make([]bool, len(tiles))
+	free := func(col, row int) bool {
+		i := row*cols + col
+		return tiles[i] && !used[i]
+	}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if !free(col, row) {
+				continue
+			}
+			// Grow right as far as possible, then down while whole rows fit
+			width := 1
+			for col+width < cols && free(col+width, row) {
+				width++
+			}
+			height := 1
+		grow:
+			for row+height < rows {
+				for c := col; c < col+width; c++ {
+					if !free(c, row+height) {
+						break grow
+					}
+				}
+				height++
+			}
+			for r := row; r < row+height; r++ {
+				for c := col; c < col+width; c++ {
+					used[r*cols+c] = true
+				}
+			}
+			rects = append(rects, TileRect{Col: float64(col), Row: float64(row), Cols: float64(width), Rows: float64(height)})
+		}
+	}
+	return rects
+}
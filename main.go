@@ -1,146 +1,22 @@
 package main
 
 import (
-	"image"
 	"image/color"
 	"log"
-	"math/rand"
+	"math"
+	"os"
 	"strconv"
-	"sync"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
-	"golang.org/x/image/font"
 )
 
-const (
-	Up = 1 << iota
-	Down
-	Left
-	Right
-)
-
-var game *Game
-
-var Dots []Dot
-
-const dev = true
-
-var Cage = Square{
-	Top:    Wall{x: cageX, y: cageY, Width: cageWidth, Height: wallThickness, Color: blue},
-	Right:  Wall{x: cageX + cageWidth - wallThickness, y: cageY, Width: wallThickness, Height: cageHeight, Color: blue},
-	Bottom: Wall{x: cageX, y: cageY + cageHeight - wallThickness, Width: cageWidth, Height: wallThickness, Color: blue},
-	Left:   Wall{x: cageX, y: cageY, Width: wallThickness, Height: cageHeight, Color: blue},
-}
-
-const (
-	sampleRate           = 44100
-	screenWidth          = 640
-	screenHeight         = 480
-	backgroundSampleRate = 44100 / 2
-)
-
-var (
-	yellow    = color.RGBA{255, 204, 85, 255}
-	green     = color.RGBA{0, 153, 76, 255}
-	blue      = color.RGBA{0, 0, 255, 0}
-	lightBlue = color.RGBA{100, 170, 230, 255}
-	red       = color.RGBA{255, 85, 85, 255}
-	orange    = color.RGBA{255, 153, 0, 255}
-)
-
-const (
-	wallThickness float64 = 12
-	cageWidth     float64 = 200
-	cageHeight    float64 = 150
-	pacmanRadius  float64 = 20
-	pacmanOffsetY float64 = WallMinimumOffset * 2
-)
-
-var (
-	pacmanPos = Point{
-		x: cageX + cageWidth/2,
-		y: cageY + cageHeight + pacmanOffsetY,
-	}
-)
-
-var cageX = (screenWidth - cageWidth) / 2
-var cageY = (screenHeight - cageHeight) / 2
-
-type Direction int
-
-const (
-	None              Direction = iota
-	WallMinimumOffset           = 60
-	WallWidth                   = 100
-	lives                       = 3
-)
-
-// Cache for font face
-var (
-	fontFace     font.Face
-	fontFaceOnce sync.Once
-	fontMutex    sync.RWMutex
-)
-
-// Cache for text measurements
-var (
-	textCache    = make(map[string]Point)
-	textCacheMux sync.RWMutex
-)
-
-type Game struct {
-	pacman            Pacman
-	cage              Square
-	mainPlayer        *audio.Player
-	mainContext       *audio.Context
-	ghost             []Pacman
-	direction         Direction
-	walls             []Wall
-	introMusicPlaying bool
-	wallSize          float64
-	gameOverState     bool
-	livesLeft         int
-	backgroundPlayer  *audio.Player
-	backgroundContext *audio.Context
-	points            int
-	audioPlayers      map[string]*audio.Player
-	audioMux          sync.RWMutex
-	level             int
-}
-
-func (g *Game) getAudioPlayer(filename string) (*audio.Player, error) {
-	g.audioMux.RLock()
-	player, exists := g.audioPlayers[filename]
-	g.audioMux.RUnlock()
-
-	if exists {
-		return player, nil
-	}
-
-	g.audioMux.Lock()
-	defer g.audioMux.Unlock()
-
-	if player, exists = g.audioPlayers[filename]; exists {
-		return player, nil
-	}
-
-	newPlayer, err := initializeAudioPlayerWithContext(filename, g.mainContext)
-	if err != nil {
-		return nil, err
-	}
-
-	g.audioPlayers[filename] = newPlayer
-	return newPlayer, nil
-}
-
 func repositionGhost(p *Pacman, g *Game) {
 	for {
-		p.x = rand.Float64() * screenWidth
-		p.y = rand.Float64() * screenHeight
-		if !g.anyCollision(p.x, p.y) {
+		p.x = rng.Float64() * g.worldWidth
+		p.y = rng.Float64() * g.worldHeight
+		if !g.anyCollision(p.x, p.y, p.radius) {
 			break
 		}
 	}
@@ -165,16 +41,13 @@ func drawText(screen *ebiten.Image, point Point, textToDisplay string, textColor
 }
 
 func (g *Game) respawnPacman() {
-	g.pacman.x = pacmanPos.x
-	g.pacman.y = pacmanPos.y
+	g.pacman.x = g.spawn.x
+	g.pacman.y = g.spawn.y
+	g.camera.Snap(g.spawn, g.worldWidth, g.worldHeight)
 	g.direction = None
+	g.pacman.lastDir = Point{}
 	g.introMusicPlaying = true
-
-	player, err := g.getAudioPlayer(audioDir + "intro.wav")
-	if err == nil {
-		g.mainPlayer = player
-		g.mainPlayer.Play()
-	}
+	g.sounds.play(soundIntro)
 
 	for i := range g.ghost {
 		// reset every ghost
@@ -184,155 +57,190 @@ func (g *Game) respawnPacman() {
 }
 
 func (g *Game) Update() error {
-	if g.gameOverState {
+	if ebiten.IsWindowBeingClosed() || g.quitting {
+		return g.closeWindow()
+	}
+	dt := g.frameTime()
+	g.watchSettings(dt)
+	g.watchDevFiles(dt)
+	if g.rebinding != nil {
+		g.updateRebind()
 		return nil
 	}
+	g.handleDisplayKeys()
+	g.handleAccessibilityKeys()
+	g.handleGameplayKeys()
+	g.handleSoundKeys()
+	g.sounds.update(dt)
+	g.updateCues()
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		g.showMinimap = !g.showMinimap
+	}
+	if g.onTitle {
+		g.updateTitle(dt)
+	}
+	if len(g.menus) > 0 {
+		g.updateMenu()
+		return nil
+	}
+	if g.demo {
+		g.updateDemo(dt)
+		return nil
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF1) {
+		g.toggleEditor()
+	}
+	if g.editing {
+		g.updateEditor(dt)
+		return nil
+	}
+	// Pause when asked, or when the window loses focus
+	if pausePressed() || !ebiten.IsFocused() {
+		g.pause()
+		return nil
+	}
+	g.advance(dt)
+	return nil
+}
+
+// One fixed step of the game
+func (g *Game) step() {
+	if g.gameOverState {
+		g.gameOverTime += simStep
+		if g.gameOverTime >= gameOverLength && !g.demo {
+			g.showTitle()
+		}
+		return
+	}
+	if g.afterJingle != nil {
+		if !g.sounds.isPlaying(soundDeath) && !g.sounds.isPlaying(soundLevelComplete) {
+			next := g.afterJingle
+			g.afterJingle = nil
+			next()
+		}
+		return
+	}
 	if g.introMusicPlaying {
-		if !g.mainPlayer.IsPlaying() {
+		if !g.sounds.isPlaying(soundIntro) {
 			g.introMusicPlaying = false
 			for i := range g.ghost {
 				repositionGhost(&g.ghost[i], g)
 			}
 		}
-		return nil
-	}
-	speed := 2.0
-
-	if g.backgroundPlayer == nil {
-		player, err := g.getAudioPlayer(audioDir + "siren.wav")
-		if err == nil {
-			g.backgroundPlayer = player
-		}
-	}
-
-	if g.backgroundPlayer != nil && !g.backgroundPlayer.IsPlaying() {
-		g.backgroundPlayer.Seek(0)
-		g.backgroundPlayer.Play()
+		return
 	}
+	speed := tunables.PacmanSpeed * simStep
+	g.sounds.play(g.sirenSound())
 
-	if ebiten.IsKeyPressed(ebiten.KeyW) || ebiten.IsKeyPressed(ebiten.KeyArrowUp) {
+	keys := &settings.Controls
+	if keyDown(keys.Up) {
 		g.direction = Up
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyS) || ebiten.IsKeyPressed(ebiten.KeyArrowDown) {
+	if keyDown(keys.Down) {
 		g.direction = Down
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyA) || ebiten.IsKeyPressed(ebiten.KeyArrowLeft) {
+	if keyDown(keys.Left) {
 		g.direction = Left
 	}
-	if ebiten.IsKeyPressed(ebiten.KeyD) || ebiten.IsKeyPressed(ebiten.KeyArrowRight) {
+	if keyDown(keys.Right) {
 		g.direction = Right
 	}
+	if g.demo {
+		g.direction = g.demoDirection()
+	}
 
-	switch g.direction {
-	case Up:
-		if g.pacman.y-speed >= g.pacman.radius && !g.anyCollision(g.pacman.x, g.pacman.y-speed) {
+	switch {
+	case g.ruleset().TileLocked:
+		g.movePacmanOnLanes()
+	case g.direction == Up:
+		if g.inBounds(g.pacman.x, g.pacman.y-speed, g.pacman.radius) && !g.sweptCollision(g.pacman.x, g.pacman.y, g.pacman.x, g.pacman.y-speed, g.pacman.radius) {
 			g.pacman.y -= speed
 		}
-	case Down:
-		if g.pacman.y+speed <= screenHeight-g.pacman.radius && !g.anyCollision(g.pacman.x, g.pacman.y+speed) {
+	case g.direction == Down:
+		if g.inBounds(g.pacman.x, g.pacman.y+speed, g.pacman.radius) && !g.sweptCollision(g.pacman.x, g.pacman.y, g.pacman.x, g.pacman.y+speed, g.pacman.radius) {
 			g.pacman.y += speed
 		}
-	case Left:
-		if g.pacman.x-speed >= g.pacman.radius && !g.anyCollision(g.pacman.x-speed, g.pacman.y) {
+	case g.direction == Left:
+		if g.inBounds(g.pacman.x-speed, g.pacman.y, g.pacman.radius) && !g.sweptCollision(g.pacman.x, g.pacman.y, g.pacman.x-speed, g.pacman.y, g.pacman.radius) {
 			g.pacman.x -= speed
 		}
-	case Right:
-		if g.pacman.x+speed <= screenWidth-g.pacman.radius && !g.anyCollision(g.pacman.x+speed, g.pacman.y) {
+	case g.direction == Right:
+		if g.inBounds(g.pacman.x+speed, g.pacman.y, g.pacman.radius) && !g.sweptCollision(g.pacman.x, g.pacman.y, g.pacman.x+speed, g.pacman.y, g.pacman.radius) {
 			g.pacman.x += speed
 		}
 	}
 
-	for i := len(Dots) - 1; i >= 0; i-- {
+	if g.followLinks(&g.pacman) {
+		g.camera.Snap(Point{x: g.pacman.x, y: g.pacman.y}, g.worldWidth, g.worldHeight)
+	}
+	g.camera.Follow(Point{x: g.pacman.x, y: g.pacman.y}, g.worldWidth, g.worldHeight)
+
+	for _, i := range g.grid.dotsWithin(Dots, g.pacman.x, g.pacman.y, g.pacman.radius) {
 		dot := Dots[i]
-		if distance(g.pacman.x, g.pacman.y, dot.x, dot.y) < g.pacman.radius {
-			Dots = append(Dots[:i], Dots[i+1:]...)
+		g.removeDot(i)
+		if dot.power {
+			g.points += pelletPoints
+		} else {
 			g.points += 10
-
-			player, err := g.getAudioPlayer(audioDir + "dot.wav")
-			if err == nil && !player.IsPlaying() {
-				player.Seek(0)
-				player.Play()
-			}
 		}
+		g.sounds.play(soundDot)
 	}
 
 	if len(Dots) == 0 {
-		println("You win!")
 		g.level++
-		println("Respwaning for next level")
-		player, err := g.getAudioPlayer(audioDir + "intermission.wav")
-		if err == nil {
-			player.Seek(0)
-			player.Play()
-			for player.IsPlaying() {
-			}
-		}
-		// Make ghosts faster based on level
-		switch {
-		case g.level == 5 || g.level == 10 || g.level == 15:
-			// Increase speed for levels 5, 10, and 15
-			for i := range g.ghost {
-				g.ghost[i].speed += 1
-			}
-		case g.level >= 20:
-			// Increase speed by 0.25 for levels 20 and above
-			for i := range g.ghost {
-				g.ghost[i].speed += 0.25
-			}
-		}
-		g.respawnPacman()
-		Dots = generateTestDots()
-
+		g.sounds.play(soundLevelComplete)
+		g.afterJingle = g.nextLevel
+		return
 	}
 
 	for _, ghost := range g.ghost {
-		if distance(g.pacman.x, g.pacman.y, ghost.x, ghost.y) < g.pacman.radius {
+		if !g.god && circlesOverlap(&g.pacman, &ghost) {
 			g.livesLeft--
 			if g.livesLeft == 0 {
 				g.gameOverState = true
-				player, err := g.getAudioPlayer(audioDir + "gameover.wav")
-				if err == nil {
-					player.Seek(0)
-					player.Play()
-				}
+				g.recordScore()
+				g.sounds.play(soundGameOver)
 			} else {
-				println("Lost a life")
-				player, err := g.getAudioPlayer(audioDir + "gameover.wav")
-				if err == nil {
-					player.Seek(0)
-					player.Play()
-					for player.IsPlaying() {
-					}
-				}
-				g.respawnPacman()
+				g.sounds.play(soundDeath)
+				g.afterJingle = g.respawnPacman
 			}
+			return
 		}
 	}
 
 	g.ghostAi(g.ghost)
-	return nil
 }
 
-func (g *Game) collidesWithWall(x, y float64) bool {
-	for _, wall := range g.walls {
-		if x+g.pacman.radius > wall.x && x-g.pacman.radius < wall.x+wall.Width &&
-			y+g.pacman.radius > wall.y && y-g.pacman.radius < wall.y+wall.Height {
-			return true
-		}
+// Set up the next level once the level complete jingle has played
+func (g *Game) nextLevel() {
+	// Make ghosts faster based on level
+	for i := range g.ghost {
+		g.ghost[i].speed += levelSpeedUp(g.level)
+	}
+	if g.mode == modeEndless {
+		g.nextMaze()
 	}
-	return false
+	g.respawnPacman()
+	g.resetDots()
+	g.applyPalette()
 }
 
-func (g *Game) collidesWithCage(x, y float64) bool {
-	if x+g.pacman.radius > g.cage.Left.x && x-g.pacman.radius < g.cage.Right.x+g.cage.Right.Width &&
-		y+g.pacman.radius > g.cage.Top.y && y-g.pacman.radius < g.cage.Bottom.y+g.cage.Bottom.Height {
-		return true
-	}
-	return false
+// Whether a body of radius r at x, y overlaps a wall
+func (g *Game) collidesWithWall(x, y, r float64) bool {
+	return g.grid.search(g.grid.walls, x-r, y-r, x+r, y+r, func(i int) bool {
+		wall := &g.walls[i]
+		return circleIntersectsRect(x, y, r, wall.x, wall.y, wall.Width, wall.Height)
+	})
+}
+
+func (g *Game) collidesWithCage(x, y, r float64) bool {
+	left, top := g.cage.Left.x, g.cage.Top.y
+	right, bottom := g.cage.Right.x+g.cage.Right.Width, g.cage.Bottom.y+g.cage.Bottom.Height
+	return circleIntersectsRect(x, y, r, left, top, right-left, bottom-top)
 }
 
-func (g *Game) anyCollision(x, y float64) bool {
-	return g.collidesWithWall(x, y) || g.collidesWithCage(x, y)
+func (g *Game) anyCollision(x, y, r float64) bool {
+	return g.collidesWithWall(x, y, r) || g.collidesWithCage(x, y, r)
 }
 
 func drawCenteredText(screen *ebiten.Image, textToDisplay string, textColor color.Color) {
@@ -356,17 +264,73 @@ func drawCenteredText(screen *ebiten.Image, textToDisplay string, textColor colo
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
-	g.pacman.Draw(screen)
+	if g.canvas == nil {
+		g.canvas = ebiten.NewImage(screenWidth, screenHeight)
+	}
+	if g.onTitle {
+		g.canvas.Clear()
+		g.topMenu().draw(g.canvas)
+		presentCanvas(screen, g.canvas)
+		return
+	}
+	w, h := int(g.worldWidth), int(g.worldHeight)
+	if g.world == nil || g.world.Bounds().Dx() != w || g.world.Bounds().Dy() != h {
+		g.world = ebiten.NewImage(w, h)
+	}
+
+	d := g.currentDrawState()
+	if !g.editing {
+		// Draw things where they'd be between the last step and the next
+		d = g.interpolate(g.accumulator / simStep)
+	}
+
+	g.world.Clear()
+	if g.editing {
+		g.editor.draw(g.world)
+	} else {
+		g.drawPlayfield(g.world, d)
+	}
+
+	g.canvas.Clear()
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(-math.Round(d.camera.x), -math.Round(d.camera.y))
+	g.canvas.DrawImage(g.world, op)
+	if g.editing {
+		g.editor.drawHUD(g.canvas)
+	} else {
+		g.drawHUD(g.canvas)
+	}
+	if !g.editing && g.showMinimap && (g.worldWidth > screenWidth || g.worldHeight > screenHeight) {
+		g.drawMinimap(g.canvas, d)
+	}
+	if m := g.topMenu(); m != nil {
+		m.draw(g.canvas)
+	}
+	g.drawReloadErrors(g.canvas)
+	presentCanvas(screen, g.canvas)
+}
+
+func (g *Game) drawPlayfield(screen *ebiten.Image, d drawState) {
+	g.drawTeleporters(screen)
+	d.pacman.Draw(screen)
 	g.cage.Draw(screen)
 	for _, dot := range Dots {
 		dot.Draw(screen)
 	}
-	for _, p := range g.ghost {
+	for i := range d.ghosts {
+		p := &d.ghosts[i]
 		p.Draw(screen)
+		if settings.Accessibility.GhostMarkers {
+			drawGhostMarker(screen, p)
+		}
 	}
 	for _, wall := range g.walls {
 		wall.Draw(screen)
 	}
+}
+
+// Text drawn over the playfield, unaffected by the camera
+func (g *Game) drawHUD(screen *ebiten.Image) {
 	if g.introMusicPlaying {
 		drawCenteredText(screen, "READY!", color.White)
 	} else {
@@ -376,23 +340,51 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	if g.gameOverState {
 		drawCenteredText(screen, "GAME OVER", color.White)
 	}
-}
-
-func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return screenWidth, screenHeight
+	if g.demo {
+		w, _ := measureText("DEMO")
+		drawText(screen, Point{x: float64(screenWidth-w) / 2, y: menuLineHeight}, "DEMO", color.White)
+	}
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		case "generate":
+			os.Exit(runGenerate(os.Args[2:]))
+		}
+	}
 
-	ebiten.SetTPS(30)
-	ebiten.SetWindowSize(screenWidth, screenHeight)
+	launch := parseLaunchFlags(os.Args[1:])
+	devMode = launch.dev
+	tunablesPath = launch.tunables
+	if tunablesPath == "" {
+		tunablesPath = defaultTunablesPath()
+	}
+	// Overrides have to be in place before anything is loaded
+	if err := useAssetOverrides(launch.assets); err != nil {
+		log.Fatal(err)
+	}
+	selectPack(settings.Display.AssetPack)
+	fontFace = generateGameFont()
+
+	ebiten.SetTPS(ebiten.SyncWithFPS)
+	ebiten.SetWindowSize(settings.Display.WindowWidth, settings.Display.WindowHeight)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	applyWindowMode()
+	ebiten.SetWindowClosingHandled(true)
 	ebiten.SetWindowTitle("PacMan Desktop")
 
-	pacmanIcon, _, err := ebitenutil.NewImageFromFile(imageDir + "pacman.png")
+	if err := setWindowIcon(); err != nil {
+		log.Fatal(err)
+	}
+
+	var err error
+	game, err = launch.newGame()
 	if err != nil {
 		log.Fatal(err)
 	}
-	ebiten.SetWindowIcon([]image.Image{pacmanIcon})
 
 	if err := ebiten.RunGame(game); err != nil {
 		log.Fatal(err)
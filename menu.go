@@ -0,0 +1,132 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// menuItem is one line of a menu. Items with a value show it after the
+// label and change it with left and right.
+type menuItem struct {
+	label    string
+	value    func() string
+	activate func()
+	adjust   func(delta int)
+}
+
+// menu is a list of items picked with the keyboard or a gamepad. Menus
+// stack, so backing out of one returns to the one underneath.
+type menu struct {
+	title    string
+	items    []menuItem
+	selected int
+	back     func() // Escape; the menu can't be backed out of if nil
+}
+
+const menuLineHeight = 36
+
+// Line height that fits all of m's items on screen
+func (m *menu) lineHeight() int {
+	return min(menuLineHeight, screenHeight/(len(m.items)+3))
+}
+
+func (g *Game) openMenu(m *menu) {
+	g.menus = append(g.menus, m)
+}
+
+func (g *Game) closeMenu() {
+	g.menus = g.menus[:len(g.menus)-1]
+}
+
+func (g *Game) topMenu() *menu {
+	if len(g.menus) == 0 {
+		return nil
+	}
+	return g.menus[len(g.menus)-1]
+}
+
+// Which way the player is pressing this frame, from keys or any gamepad
+func menuInput() (dx, dy int, activate, back bool) {
+	key := func(keys ...ebiten.Key) bool {
+		for _, k := range keys {
+			if inpututil.IsKeyJustPressed(k) {
+				return true
+			}
+		}
+		return false
+	}
+	pad := func(button ebiten.StandardGamepadButton) bool {
+		for _, id := range ebiten.AppendGamepadIDs(nil) {
+			if inpututil.IsStandardGamepadButtonJustPressed(id, button) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case key(ebiten.KeyArrowUp, ebiten.KeyW) || pad(ebiten.StandardGamepadButtonLeftTop):
+		dy = -1
+	case key(ebiten.KeyArrowDown, ebiten.KeyS) || pad(ebiten.StandardGamepadButtonLeftBottom):
+		dy = 1
+	case key(ebiten.KeyArrowLeft, ebiten.KeyA) || pad(ebiten.StandardGamepadButtonLeftLeft):
+		dx = -1
+	case key(ebiten.KeyArrowRight, ebiten.KeyD) || pad(ebiten.StandardGamepadButtonLeftRight):
+		dx = 1
+	}
+	activate = (key(ebiten.KeyEnter, ebiten.KeySpace) && !ebiten.IsKeyPressed(ebiten.KeyAlt)) ||
+		pad(ebiten.StandardGamepadButtonRightBottom)
+	back = key(ebiten.KeyEscape, ebiten.KeyBackspace) || pad(ebiten.StandardGamepadButtonRightRight)
+	return dx, dy, activate, back
+}
+
+func (g *Game) updateMenu() {
+	m := g.topMenu()
+	dx, dy, activate, back := menuInput()
+	m.selected = (m.selected + dy + len(m.items)) % len(m.items)
+	item := m.items[m.selected]
+	switch {
+	case back && m.back != nil:
+		m.back()
+	case activate && item.activate != nil:
+		item.activate()
+	case activate && item.adjust != nil:
+		item.adjust(1)
+	case dx != 0 && item.adjust != nil:
+		item.adjust(dx)
+	}
+}
+
+// Draw the menu centred over whatever is underneath, dimmed
+func (m *menu) draw(screen *ebiten.Image) {
+	vector.DrawFilledRect(screen, 0, 0, screenWidth, screenHeight, color.RGBA{0, 0, 0, 192}, false)
+
+	lineHeight := m.lineHeight()
+	top := (screenHeight - (len(m.items)+2)*lineHeight) / 2
+	w, _ := measureText(m.title)
+	drawText(screen, Point{x: float64(screenWidth-w) / 2, y: float64(top + lineHeight)}, m.title, currentPalette().Pacman)
+	for i, item := range m.items {
+		line := item.label
+		if item.value != nil {
+			line += ": " + item.value()
+		}
+		clr := color.Color(color.White)
+		if i == m.selected {
+			line = "> " + line + " <"
+			clr = currentPalette().Pacman
+		}
+		w, _ := measureText(line)
+		y := top + (i+3)*lineHeight
+		drawText(screen, Point{x: float64(screenWidth-w) / 2, y: float64(y)}, line, clr)
+	}
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
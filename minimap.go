@@ -0,0 +1,49 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+const (
+	minimapSize   = 120.0 // Longest side, in logical pixels
+	minimapMargin = 8.0
+)
+
+// Draw a scaled down overview of the maze in the top right corner
+func (g *Game) drawMinimap(screen *ebiten.Image, d drawState) {
+	scale := math.Min(minimapSize/g.worldWidth, minimapSize/g.worldHeight)
+	originX := screenWidth - g.worldWidth*scale - minimapMargin
+	originY := minimapMargin
+
+	// Convert a world rectangle to minimap space, at least a pixel across
+	rect := func(x, y, w, h float64) (float32, float32, float32, float32) {
+		return float32(originX + x*scale), float32(originY + y*scale),
+			float32(math.Max(w*scale, 1)), float32(math.Max(h*scale, 1))
+	}
+
+	x, y, w, h := rect(0, 0, g.worldWidth, g.worldHeight)
+	vector.DrawFilledRect(screen, x, y, w, h, color.RGBA{0, 0, 0, 200}, false)
+
+	for _, wall := range g.walls {
+		x, y, w, h := rect(wall.x, wall.y, wall.Width, wall.Height)
+		vector.DrawFilledRect(screen, x, y, w, h, wall.Color, false)
+	}
+	for _, dot := range Dots {
+		x, y, w, h := rect(dot.x, dot.y, 0, 0)
+		vector.DrawFilledRect(screen, x, y, w, h, dot.color, false)
+	}
+	for _, ghost := range d.ghosts {
+		x, y, _, _ := rect(ghost.x, ghost.y, 0, 0)
+		vector.DrawFilledCircle(screen, x, y, 2, ghost.color, false)
+	}
+	x, y, _, _ = rect(d.pacman.x, d.pacman.y, 0, 0)
+	vector.DrawFilledCircle(screen, x, y, 2.5, d.pacman.color, false)
+
+	// Outline of what the camera can currently see
+	x, y, w, h = rect(d.camera.x, d.camera.y, screenWidth, screenHeight)
+	vector.StrokeRect(screen, x, y, w, h, 1, color.White, false)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+type GameMode int
+
+const (
+	modeClassic GameMode = iota // One maze, the same every level
+	modeEndless                 // A freshly generated maze every level
+)
+
+var gameModeNames = []string{"classic", "endless"}
+
+func (m GameMode) String() string {
+	return gameModeNames[m]
+}
+
+// Ruleset is how the game plays, whichever mode it's in
+type Ruleset struct {
+	Name       string
+	TileLocked bool // Arcade movement along tile lanes, at the arcade's speeds
+}
+
+var (
+	modernRules = Ruleset{Name: "modern"}
+	arcadeRules = Ruleset{Name: "arcade", TileLocked: true}
+	rulesets    = []Ruleset{modernRules, arcadeRules}
+)
+
+func findRuleset(name string) Ruleset {
+	for _, r := range rulesets {
+		if r.Name == name {
+			return r
+		}
+	}
+	return modernRules
+}
+
+// The rules in play. The demo always plays by the arcade's, since it steers
+// along the lanes.
+func (g *Game) ruleset() Ruleset {
+	if g.demo {
+		return arcadeRules
+	}
+	return findRuleset(settings.Gameplay.Ruleset)
+}
+
+func rulesetNames() []string {
+	return Map(rulesets, func(r Ruleset) string { return r.Name })
+}
+
+// Difficulty scales how fast the ghosts are
+type Difficulty struct {
+	Name       string
+	GhostSpeed float64 // Multiplies every ghost speed
+}
+
+var (
+	easyDifficulty   = Difficulty{Name: "easy", GhostSpeed: 0.85}
+	normalDifficulty = Difficulty{Name: "normal", GhostSpeed: 1}
+	hardDifficulty   = Difficulty{Name: "hard", GhostSpeed: 1.15}
+	difficulties     = []Difficulty{easyDifficulty, normalDifficulty, hardDifficulty}
+)
+
+func currentDifficulty() Difficulty {
+	for _, d := range difficulties {
+		if d.Name == settings.Gameplay.Difficulty {
+			return d
+		}
+	}
+	return normalDifficulty
+}
+
+func difficultyNames() []string {
+	return Map(difficulties, func(d Difficulty) string { return d.Name })
+}
+
+// Switch ruleset, taking effect straight away
+func (g *Game) setRuleset(name string) {
+	settings.Gameplay.Ruleset = name
+	g.pacman.lastDir = Point{}
+	for i := range g.ghost {
+		g.ghost[i].lastDir = Point{}
+	}
+}
+
+// F7 switches to the next ruleset
+func (g *Game) handleGameplayKeys() {
+	if !inpututil.IsKeyJustPressed(ebiten.KeyF7) {
+		return
+	}
+	g.setRuleset(cycleName(rulesetNames(), settings.Gameplay.Ruleset, 1))
+	g.settingsChanged()
+}
+
+// The same seed and level always give the same maze
+func endlessMaze(seed int64, level int) *Level {
+	return generateMaze(seed+int64(level), endlessNodesX, endlessNodesY)
+}
+
+// Move on to the next generated maze, keeping the ghosts' speed
+func (g *Game) nextMaze() {
+	speeds := Map(g.ghost, func(p Pacman) float64 { return p.speed })
+	g.useLevel(endlessMaze(g.seed, g.level))
+	for i := range min(len(g.ghost), len(speeds)) {
+		g.ghost[i].speed = speeds[i]
+	}
+}
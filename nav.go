@@ -0,0 +1,244 @@
+package main
+
+import (
+	"math"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// navGrid records where a Pacman-sized body can be, sampled on a lattice,
+// plus the extra connections that tunnels and teleporters make between
+// distant points. Ghosts path over it so they know about shortcuts through
+// the links. Nodes sit on tile corners, since that is where a body two tiles
+// in radius fits between tile-aligned walls.
+type navGrid struct {
+	step       float64 // Distance between nodes
+	cols, rows int
+	open       []bool
+	links      map[int][]int
+	linksTo    map[int][]int            // Links the other way round, for searching back from a target
+	distances  *lru.Cache[int, []int32] // Steps to a target node from every node, by target
+}
+
+// How many targets' distances to keep. Each ghost has one target at a time,
+// and it only moves node every few steps.
+const navCacheSize = 16
+
+func (g *Game) buildNavGrid(step float64) *navGrid {
+	n := &navGrid{
+		step:    step,
+		cols:    int(g.worldWidth / step),
+		rows:    int(g.worldHeight / step),
+		links:   make(map[int][]int),
+		linksTo: make(map[int][]int),
+	}
+	n.distances, _ = lru.New[int, []int32](navCacheSize)
+	n.open = make([]bool, n.cols*n.rows)
+	for row := 0; row < n.rows; row++ {
+		for col := 0; col < n.cols; col++ {
+			c := n.centre(n.index(col, row))
+			n.open[n.index(col, row)] = !g.anyCollision(c.x, c.y, g.pacman.radius)
+		}
+	}
+
+	link := func(from, to int) {
+		if n.open[from] && n.open[to] {
+			n.links[from] = append(n.links[from], to)
+			n.linksTo[to] = append(n.linksTo[to], from)
+		}
+	}
+
+	// Tunnels join nodes on one edge to the same nodes on the opposite edge
+	for row := 0; row < n.rows; row++ {
+		left, right := n.index(0, row), n.index(n.cols-1, row)
+		y := n.centre(left).y
+		if g.inTunnel(n.centre(left).x, y) || g.inTunnel(n.centre(right).x, y) {
+			link(left, right)
+			link(right, left)
+		}
+	}
+	for col := 0; col < n.cols; col++ {
+		top, bottom := n.index(col, 0), n.index(col, n.rows-1)
+		x := n.centre(top).x
+		if g.inTunnel(x, n.centre(top).y) || g.inTunnel(x, n.centre(bottom).y) {
+			link(top, bottom)
+			link(bottom, top)
+		}
+	}
+
+	// Every node on a pad leads to the centre of the other pad
+	for _, t := range g.teleporters {
+		for _, pads := range [][2]Wall{{t.a, t.b}, {t.b, t.a}} {
+			exit, ok := n.cellAt(pads[1].centre().x, pads[1].centre().y)
+			if !ok {
+				continue
+			}
+			for i := range n.open {
+				c := n.centre(i)
+				if pads[0].contains(c.x, c.y) {
+					link(i, exit)
+				}
+			}
+		}
+	}
+	return n
+}
+
+func (n *navGrid) index(col, row int) int {
+	return row*n.cols + col
+}
+
+func (n *navGrid) centre(i int) Point {
+	return Point{x: float64(i%n.cols) * n.step, y: float64(i/n.cols) * n.step}
+}
+
+// Node nearest to x, y
+func (n *navGrid) cellAt(x, y float64) (int, bool) {
+	col, row := int(math.Round(x/n.step)), int(math.Round(y/n.step))
+	if col < 0 || row < 0 || col >= n.cols || row >= n.rows {
+		return 0, false
+	}
+	return n.index(col, row), true
+}
+
+// Nearest open node to x, y, looking a tile or so out
+func (n *navGrid) nearestOpen(x, y float64) (int, bool) {
+	searchRadius := int(math.Ceil(2 * tileSize / n.step))
+	best, found := 0, false
+	bestDist := 0.0
+	for dy := -searchRadius; dy <= searchRadius; dy++ {
+		for dx := -searchRadius; dx <= searchRadius; dx++ {
+			i, ok := n.cellAt(x+float64(dx)*n.step, y+float64(dy)*n.step)
+			if !ok || !n.open[i] {
+				continue
+			}
+			c := n.centre(i)
+			if d := distance(x, y, c.x, c.y); !found || d < bestDist {
+				best, bestDist, found = i, d, true
+			}
+		}
+	}
+	return best, found
+}
+
+func (n *navGrid) neighbours(i int) []int {
+	return append(n.gridNeighbours(make([]int, 0, 4+len(n.links[i])), i), n.links[i]...)
+}
+
+// Nodes that lead to i: the same grid neighbours, but links into it
+func (n *navGrid) predecessors(result []int, i int) []int {
+	return append(n.gridNeighbours(result, i), n.linksTo[i]...)
+}
+
+// The nodes either side of i, appended to result
+func (n *navGrid) gridNeighbours(result []int, i int) []int {
+	col, row := i%n.cols, i/n.cols
+	if col > 0 {
+		result = append(result, i-1)
+	}
+	if col < n.cols-1 {
+		result = append(result, i+1)
+	}
+	if row > 0 {
+		result = append(result, i-n.cols)
+	}
+	if row < n.rows-1 {
+		result = append(result, i+n.cols)
+	}
+	return result
+}
+
+// Whether stepping from a to b goes through a tunnel or teleporter
+func (n *navGrid) isLink(a, b int) bool {
+	for _, to := range n.links[a] {
+		if to == b {
+			return true
+		}
+	}
+	return false
+}
+
+// Steps to the given node from every node, -1 where there's no way
+// through. Searched back from the target once and kept, so every ghost
+// heading the same way shares it and it's only searched again when the
+// target moves to another node.
+func (n *navGrid) distancesTo(to int) []int32 {
+	if dist, ok := n.distances.Get(to); ok {
+		return dist
+	}
+	dist := make([]int32, len(n.open))
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[to] = 0
+	queue := []int{to}
+	var before []int
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		before = n.predecessors(before[:0], current)
+		for _, prev := range before {
+			if n.open[prev] && dist[prev] == -1 {
+				dist[prev] = dist[current] + 1
+				queue = append(queue, prev)
+			}
+		}
+	}
+	n.distances.Add(to, dist)
+	return dist
+}
+
+// The node after from on a shortest path to to. False if from is to, or
+// there's no way through.
+func (n *navGrid) next(from, to int) (int, bool) {
+	dist := n.distancesTo(to)
+	if from == to || dist[from] <= 0 {
+		return 0, false
+	}
+	var buf [8]int
+	for _, next := range append(n.gridNeighbours(buf[:0], from), n.links[from]...) {
+		if n.open[next] && dist[next] == dist[from]-1 {
+			return next, true
+		}
+	}
+	return 0, false
+}
+
+// Point to head for to use the link from a to b. Tunnels need the ghost to
+// carry on past the edge of the world, teleporters just need it on the pad.
+func (n *navGrid) linkEntrance(a, b int) Point {
+	c := n.centre(a)
+	aCol, aRow, bCol, bRow := a%n.cols, a/n.cols, b%n.cols, b/n.cols
+	switch {
+	case aRow == bRow && aCol == 0 && bCol == n.cols-1:
+		c.x -= tileSize
+	case aRow == bRow && aCol == n.cols-1 && bCol == 0:
+		c.x += n.step + tileSize
+	case aCol == bCol && aRow == 0 && bRow == n.rows-1:
+		c.y -= tileSize
+	case aCol == bCol && aRow == n.rows-1 && bRow == 0:
+		c.y += n.step + tileSize
+	}
+	return c
+}
+
+// Every node that can be reached from the given one
+func (n *navGrid) reachable(from int) []bool {
+	seen := make([]bool, len(n.open))
+	if !n.open[from] {
+		return seen
+	}
+	seen[from] = true
+	queue := []int{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range n.neighbours(current) {
+			if n.open[next] && !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return seen
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// The name delta places on from current, wrapping round
+func cycleName(names []string, current string, delta int) string {
+	i := 0
+	for j, name := range names {
+		if name == current {
+			i = j
+		}
+	}
+	return names[((i+delta)%len(names)+len(names))%len(names)]
+}
+
+// A submenu that Escape or its last item backs out of
+func (g *Game) subMenu(title string, items ...menuItem) *menu {
+	return &menu{
+		title: title,
+		items: append(items, menuItem{label: "Back", activate: g.closeMenu}),
+		back:  g.closeMenu,
+	}
+}
+
+// Options that can be changed from the menus, applied as soon as they change
+func (g *Game) optionsMenu() *menu {
+	a := &settings.Accessibility
+	d := &settings.Display
+	p := &settings.Gameplay
+	changed := g.settingsChanged
+	toggle := func(b *bool) func(int) {
+		return func(int) {
+			*b = !*b
+			changed()
+		}
+	}
+	choice := func(label string, value *string, names func() []string) menuItem {
+		return menuItem{label: label, value: func() string { return *value }, adjust: func(delta int) {
+			*value = cycleName(names(), *value, delta)
+			changed()
+		}}
+	}
+	volume := func(label string, v *float64) menuItem {
+		return menuItem{label: label, value: func() string {
+			return fmt.Sprintf("%d%%", int(math.Round(*v*100)))
+		}, adjust: func(delta int) {
+			*v = math.Round(min(max(*v+float64(delta)*0.1, 0), 1)*10) / 10
+			changed()
+		}}
+	}
+	open := func(m func() *menu) func() {
+		return func() { g.openMenu(m()) }
+	}
+
+	audioMenu := func() *menu {
+		items := []menuItem{volume("Master", &settings.Audio.Master)}
+		for ch := range channelCount {
+			items = append(items, volume(channelNames[ch], settings.Audio.channelVolume(ch)))
+		}
+		items = append(items,
+			menuItem{label: "Mute", value: func() string { return onOff(settings.Audio.Muted) }, adjust: toggle(&settings.Audio.Muted)},
+			menuItem{label: "Chiptune", value: func() string { return onOff(settings.Audio.Chiptune) }, adjust: toggle(&settings.Audio.Chiptune)},
+		)
+		return g.subMenu("AUDIO", items...)
+	}
+	controlsMenu := func() *menu {
+		var items []menuItem
+		for _, b := range settings.Controls.all() {
+			keys := b.keys
+			items = append(items, menuItem{label: b.action, value: func() string {
+				if g.rebinding == keys {
+					return "press a key"
+				}
+				return keyNames(*keys)
+			}, activate: func() { g.rebind(keys) }})
+		}
+		for _, h := range hotkeys {
+			name := h[1]
+			items = append(items, menuItem{label: h[0], value: func() string { return name }})
+		}
+		items = append(items, menuItem{label: "Reset keys", activate: func() {
+			settings.Controls = defaultBindings()
+			changed()
+		}})
+		return g.subMenu("CONTROLS", items...)
+	}
+	displayMenu := func() *menu {
+		return g.subMenu("DISPLAY",
+			choice("Window", &d.WindowMode, func() []string { return windowModes }),
+			menuItem{label: "Integer scale", value: func() string { return onOff(d.IntegerScale) }, adjust: toggle(&d.IntegerScale)},
+			choice("Skin", &d.AssetPack, packNames),
+		)
+	}
+	accessibilityMenu := func() *menu {
+		return g.subMenu("ACCESSIBILITY",
+			choice("Palette", &a.Palette, paletteNames),
+			menuItem{label: "Ghost markers", value: func() string { return onOff(a.GhostMarkers) }, adjust: toggle(&a.GhostMarkers)},
+			menuItem{label: "Thick walls", value: func() string { return onOff(a.ThickWalls) }, adjust: toggle(&a.ThickWalls)},
+			menuItem{label: "Big dots", value: func() string { return onOff(a.HighContrastDots) }, adjust: toggle(&a.HighContrastDots)},
+			menuItem{label: "Ghost cues", value: func() string { return onOff(a.GhostCues) }, adjust: toggle(&a.GhostCues)},
+		)
+	}
+	gameplayMenu := func() *menu {
+		return g.subMenu("GAMEPLAY",
+			menuItem{label: "Rules", value: func() string { return p.Ruleset }, adjust: func(delta int) {
+				g.setRuleset(cycleName(rulesetNames(), p.Ruleset, delta))
+				changed()
+			}},
+			choice("Difficulty", &p.Difficulty, difficultyNames),
+			menuItem{label: "Lives", value: func() string { return fmt.Sprint(p.Lives) }, adjust: func(delta int) {
+				p.Lives = min(max(p.Lives+delta, minLives), maxLives)
+				changed()
+			}},
+		)
+	}
+
+	return g.subMenu("OPTIONS",
+		menuItem{label: "Audio", activate: open(audioMenu)},
+		menuItem{label: "Controls", activate: open(controlsMenu)},
+		menuItem{label: "Display", activate: open(displayMenu)},
+		menuItem{label: "Accessibility", activate: open(accessibilityMenu)},
+		menuItem{label: "Gameplay", activate: open(gameplayMenu)},
+	)
+}
+
+// Keys that can't be rebound, for the controls menu
+var hotkeys = [][2]string{
+	{"Minimap", "Tab"},
+	{"Editor", "F1"},
+	{"Palette", "F2"},
+	{"Markers/walls/dots", "F3-F5"},
+	{"Integer scale", "F6"},
+	{"Rules", "F7"},
+	{"Ghost cues", "F8"},
+	{"Fullscreen", "F11"},
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font/opentype"
+)
+
+// Asset packs reskin the game without rebuilding it. A pack is a directory
+// or zip in the packs directory (under the config directory) with a
+// pack.json manifest, e.g.
+//
+//	{
+//	  "name": "holiday",
+//	  "sprites": {"icon": "img/snowman.png"},
+//	  "sounds": {"dot": "audio/bell.ogg", "siren": "audio/sleigh.wav"},
+//	  "font": "font/festive.ttf",
+//	  "palette": {"pacman": "#ff0000", "walls": ["#00aa00", "#ffffff"]}
+//	}
+//
+// Anything the manifest leaves out comes from the built-in assets. Files a
+// pack has at the built-in paths ("audio/dot.wav") are used without being
+// listed.
+
+const (
+	packsDirName    = "packs"
+	packManifest    = "pack.json"
+	defaultPackName = "default"
+)
+
+// PackManifest is a pack's pack.json
+type PackManifest struct {
+	Name    string            `json:"name"`
+	Sprites map[string]string `json:"sprites"` // By name, see packSprites
+	Sounds  map[string]string `json:"sounds"`  // By name, see packSounds
+	Font    string            `json:"font"`
+	Palette *PackPalette      `json:"palette"`
+}
+
+// PackPalette replaces colours of the classic palette, as "#rrggbb" or
+// "#rrggbbaa". Colours left out keep their classic value.
+type PackPalette struct {
+	Pacman string   `json:"pacman"`
+	Ghosts []string `json:"ghosts"` // Up to one per ghost variety
+	Walls  []string `json:"walls"`
+	Cage   string   `json:"cage"`
+	Dot    string   `json:"dot"`
+}
+
+// An asset pack ready to use
+type assetPack struct {
+	name    string
+	source  string            // Directory or zip it was loaded from, "" for the built-in pack
+	archive io.Closer         // The open zip, if it's one
+	files   fs.FS             // Nil for the built-in pack
+	paths   map[string]string // Built-in asset path to the pack's file standing in for it
+	palette *Palette          // Replaces the classic palette, if the pack has one
+}
+
+// Sprites a pack can replace, by the name the manifest uses
+var packSprites = map[string]string{
+	"icon": imageDir + "pacman.png",
+}
+
+// Sounds a pack can replace, named after the built-in file ("dot" for
+// audio/dot.wav)
+func packSounds() map[string]string {
+	names := make(map[string]string)
+	for _, def := range sounds {
+		base := path.Base(def.file)
+		names[strings.TrimSuffix(base, path.Ext(base))] = def.file
+	}
+	return names
+}
+
+var (
+	packs       = []*assetPack{{name: defaultPackName}}
+	currentPack = packs[0]
+)
+
+func packNames() []string {
+	return Map(packs, func(p *assetPack) string { return p.name })
+}
+
+func findPack(name string) *assetPack {
+	for _, p := range packs {
+		if p.name == name {
+			return p
+		}
+	}
+	return packs[0]
+}
+
+// Find the packs in the packs directory, logging any that can't be used
+func findPacks() {
+	config, err := configDir()
+	if err != nil {
+		return
+	}
+	dir := filepath.Join(config, packsDirName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		file := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() && !strings.EqualFold(filepath.Ext(file), ".zip") {
+			continue
+		}
+		pack, err := openPack(file)
+		if err != nil {
+			log.Printf("skipping asset pack %s: %v", file, err)
+			continue
+		}
+		if slices.Contains(packNames(), pack.name) {
+			log.Printf("skipping asset pack %s: there's already a pack called %q", file, pack.name)
+			continue
+		}
+		packs = append(packs, pack)
+	}
+}
+
+// Load the pack in a directory or zip
+func openPack(file string) (*assetPack, error) {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if info, err := os.Stat(file); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		pack, err := loadPack(os.DirFS(file), name)
+		if err == nil {
+			pack.source = file
+		}
+		return pack, err
+	}
+	// Kept open for as long as the pack is in use
+	z, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	pack, err := loadPack(z, name)
+	if err != nil {
+		z.Close()
+		return nil, err
+	}
+	pack.source, pack.archive = file, z
+	return pack, nil
+}
+
+// Read a pack's manifest and check everything it lists is there. The pack
+// is called name if the manifest doesn't say.
+func loadPack(files fs.FS, name string) (*assetPack, error) {
+	// Zips are often made of the folder, rather than what's in it
+	if _, err := fs.Stat(files, packManifest); err != nil {
+		if entries, _ := fs.ReadDir(files, "."); len(entries) == 1 && entries[0].IsDir() {
+			if sub, err := fs.Sub(files, entries[0].Name()); err == nil {
+				files = sub
+			}
+		}
+	}
+	data, err := fs.ReadFile(files, packManifest)
+	if err != nil {
+		return nil, err
+	}
+	var m PackManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", packManifest, err)
+	}
+
+	pack := &assetPack{name: m.Name, files: files, paths: make(map[string]string)}
+	if pack.name == "" {
+		pack.name = name
+	}
+	replace := func(what string, known map[string]string, listed map[string]string) error {
+		for name, file := range listed {
+			builtIn, ok := known[name]
+			if !ok {
+				return fmt.Errorf("unknown %s %q", what, name)
+			}
+			pack.paths[builtIn] = file
+		}
+		return nil
+	}
+	if err := replace("sprite", packSprites, m.Sprites); err != nil {
+		return nil, err
+	}
+	if err := replace("sound", packSounds(), m.Sounds); err != nil {
+		return nil, err
+	}
+	if m.Font != "" {
+		data, err := fs.ReadFile(files, m.Font)
+		if err != nil {
+			return nil, err
+		}
+		// A font that won't parse would stop the game when switched to
+		if _, err := opentype.Parse(data); err != nil {
+			return nil, fmt.Errorf("font %s: %w", m.Font, err)
+		}
+		pack.paths[retroFont] = m.Font
+	}
+	for _, file := range pack.paths {
+		if _, err := fs.Stat(files, file); err != nil {
+			return nil, err
+		}
+	}
+	if m.Palette != nil {
+		if pack.palette, err = m.Palette.palette(); err != nil {
+			return nil, fmt.Errorf("palette: %w", err)
+		}
+	}
+	return pack, nil
+}
+
+// The classic palette with the pack's colours in place of its own
+func (pp *PackPalette) palette() (*Palette, error) {
+	p := classicPalette
+	var err error
+	set := func(c *color.Color, hex string) {
+		if hex == "" || err != nil {
+			return
+		}
+		*c, err = parseColour(hex)
+	}
+	set(&p.Pacman, pp.Pacman)
+	set(&p.Cage, pp.Cage)
+	set(&p.Dot, pp.Dot)
+	if len(pp.Ghosts) > len(p.Ghosts) {
+		return nil, fmt.Errorf("%d ghost colours, there are only %d ghosts", len(pp.Ghosts), len(p.Ghosts))
+	}
+	for i, hex := range pp.Ghosts {
+		set(&p.Ghosts[i], hex)
+	}
+	if len(pp.Walls) > 0 {
+		p.Walls = make([]color.Color, len(pp.Walls))
+		for i, hex := range pp.Walls {
+			set(&p.Walls[i], hex)
+		}
+	}
+	return &p, err
+}
+
+// Parse "#rrggbb" or "#rrggbbaa"
+func parseColour(hex string) (color.Color, error) {
+	var c color.RGBA
+	c.A = 255
+	var err error
+	switch len(hex) {
+	case 7:
+		_, err = fmt.Sscanf(hex, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	case 9:
+		_, err = fmt.Sscanf(hex, "#%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	default:
+		err = fmt.Errorf("not #rrggbb")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bad colour %q: %w", hex, err)
+	}
+	return c, nil
+}
+
+// The file to load for a built-in asset path, which the current pack may
+// have its own for
+func assetPath(path string) string {
+	if file, ok := currentPack.paths[path]; ok {
+		return file
+	}
+	return path
+}
+
+// Cache key for an asset, so each pack's files are cached apart and
+// switching back to a pack doesn't load it again
+func assetKey(path string) string {
+	return currentPack.name + ":" + path
+}
+
+// Drop the cached assets whose keys match, so they're read again next time
+func forgetAssets(match func(key string) bool) {
+	forget := func(keys []string, remove func(string) bool) {
+		for _, key := range keys {
+			if match(key) {
+				remove(key)
+			}
+		}
+	}
+	forget(audioCache.Keys(), audioCache.Remove)
+	forget(fontBytesCache.Keys(), fontBytesCache.Remove)
+}
+
+// Make the named pack the current one, loading assets from it first
+func selectPack(name string) {
+	currentPack = findPack(name)
+	buildAssets()
+}
+
+// Switch to the pack in the settings, reloading everything that came from
+// the one before
+func (g *Game) usePack() {
+	if currentPack.name == settings.Display.AssetPack {
+		return
+	}
+	selectPack(settings.Display.AssetPack)
+	if err := g.reloadAssets(); err != nil {
+		log.Printf("failed to load asset pack %s: %v", currentPack.name, err)
+	}
+}
+
+// Load the font, icon and sounds again, after the files they come from
+// change. A font that won't load keeps the one already in use.
+func (g *Game) reloadAssets() error {
+	data, err := fs.ReadFile(assets, assetPath(retroFont))
+	if err != nil {
+		return err
+	}
+	if _, err := opentype.Parse(data); err != nil {
+		return fmt.Errorf("font: %w", err)
+	}
+	fontFaceOnce = sync.Once{}
+	fontFace = generateGameFont()
+	textCacheMux.Lock()
+	textCache = make(map[string]Point)
+	textCacheMux.Unlock()
+	g.sounds.reload()
+	return setWindowIcon()
+}
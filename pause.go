@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// The pause keys or a gamepad's Start button
+func pausePressed() bool {
+	if keyJustPressed(settings.Controls.Pause) {
+		return true
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if inpututil.IsStandardGamepadButtonJustPressed(id, ebiten.StandardGamepadButtonCenterRight) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stop the game where it is, along with anything playing
+func (g *Game) pause() {
+	g.sounds.pause()
+	g.openMenu(g.pauseMenu())
+}
+
+func (g *Game) resume() {
+	g.menus = nil
+	g.sounds.resume()
+}
+
+func (g *Game) pauseMenu() *menu {
+	return &menu{
+		title: "PAUSED",
+		items: []menuItem{
+			{label: "Resume", activate: g.resume},
+			{label: "Restart", activate: func() {
+				g.menus = nil
+				g.sounds.stop()
+				g.restart()
+			}},
+			{label: "Options", activate: func() { g.openMenu(g.optionsMenu()) }},
+			{label: "Quit to title", activate: g.showTitle},
+		},
+		back: g.resume,
+	}
+}
+
+// Start over from the starting level, on the current maze or the generated
+// one for that level, with the ghosts as fast as they'd be by then
+func (g *Game) restart() {
+	g.level = g.startLevel
+	if g.mode == modeEndless {
+		g.useLevel(endlessMaze(g.seed, g.level))
+	} else {
+		g.useLevel(g.classicMaze)
+	}
+	g.catchUpGhostSpeeds()
+	g.points = 0
+	g.livesLeft = settings.Gameplay.Lives
+	if g.startLives > 0 {
+		g.livesLeft = g.startLives
+	}
+	g.gameOverState = false
+	g.gameOverTime = 0
+	g.afterJingle = nil
+	g.respawnPacman()
+}
+
+// Speed the ghosts up as much as they would have by the current level
+func (g *Game) catchUpGhostSpeeds() {
+	for level := 2; level <= g.level; level++ {
+		for i := range g.ghost {
+			g.ghost[i].speed += levelSpeedUp(level)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package main
+
+const (
+	pelletRadius = 8.0
+	pelletPoints = 50
+)
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const settingsFile = "settings.json"
+
+// Settings are the player's preferences, persisted between runs
+type Settings struct {
+	Audio         AudioSettings         `json:"audio"`
+	Controls      Bindings              `json:"controls"`
+	Accessibility AccessibilitySettings `json:"accessibility"`
+	Display       DisplaySettings       `json:"display"`
+	Gameplay      GameplaySettings      `json:"gameplay"`
+}
+
+// AudioSettings control how loud the game is. Each volume is 0 to 1, and
+// every channel's is scaled by the master volume.
+type AudioSettings struct {
+	Master   float64 `json:"master"`
+	Music    float64 `json:"music"`
+	Siren    float64 `json:"siren"`
+	Effects  float64 `json:"effects"`
+	Jingles  float64 `json:"jingles"`
+	Muted    bool    `json:"muted"`
+	Chiptune bool    `json:"chiptune"` // Synthesised sounds instead of the sound files
+}
+
+func (a *AudioSettings) channelVolume(ch channel) *float64 {
+	switch ch {
+	case channelMusic:
+		return &a.Music
+	case channelSiren:
+		return &a.Siren
+	case channelJingles:
+		return &a.Jingles
+	}
+	return &a.Effects
+}
+
+// AccessibilitySettings control how the playfield is drawn
+type AccessibilitySettings struct {
+	Palette          string `json:"palette"`
+	GhostMarkers     bool   `json:"ghostMarkers"`
+	ThickWalls       bool   `json:"thickWalls"`
+	HighContrastDots bool   `json:"highContrastDots"`
+	GhostCues        bool   `json:"ghostCues"` // Each ghost sounds a tone from where it is
+}
+
+// DisplaySettings control the window and how the playfield is scaled into it
+type DisplaySettings struct {
+	WindowWidth  int    `json:"windowWidth"`
+	WindowHeight int    `json:"windowHeight"`
+	WindowMode   string `json:"windowMode"`
+	IntegerScale bool   `json:"integerScale"`
+	AssetPack    string `json:"assetPack"` // Skin the game is drawn and sounds with
+}
+
+// GameplaySettings change the rules of the game itself
+type GameplaySettings struct {
+	Ruleset    string `json:"ruleset"`
+	Lives      int    `json:"lives"` // Lives at the start of a game
+	Difficulty string `json:"difficulty"`
+}
+
+const (
+	minLives = 1
+	maxLives = 9
+)
+
+var settings = defaultSettings()
+
+func defaultSettings() Settings {
+	return Settings{
+		Audio: AudioSettings{
+			Master:  1,
+			Music:   1,
+			Siren:   1,
+			Effects: 1,
+			Jingles: 1,
+		},
+		Controls: defaultBindings(),
+		Accessibility: AccessibilitySettings{
+			Palette: classicPalette.Name,
+		},
+		Display: DisplaySettings{
+			WindowWidth:  screenWidth,
+			WindowHeight: screenHeight,
+			WindowMode:   windowed,
+			AssetPack:    defaultPackName,
+		},
+		Gameplay: GameplaySettings{
+			Ruleset:    modernRules.Name,
+			Lives:      3,
+			Difficulty: normalDifficulty.Name,
+		},
+	}
+}
+
+// Directory the settings (and anything else we persist) live in
+func configDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pacman-desktop"), nil
+}
+
+func settingsPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, settingsFile), nil
+}
+
+// Load settings from the config directory, falling back to defaults
+func loadSettings() (Settings, error) {
+	s := defaultSettings()
+	path, err := settingsPath()
+	if err != nil {
+		return s, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if info, err := os.Stat(path); err == nil {
+		settingsModified = info.ModTime()
+	}
+	// Unmarshal on top of the defaults so missing keys keep their default value
+	if err := json.Unmarshal(data, &s); err != nil {
+		return defaultSettings(), err
+	}
+	for _, problem := range s.validate() {
+		log.Printf("settings: %s", problem)
+	}
+	return s, nil
+}
+
+// Put anything out of range or unknown back to a value the game can use,
+// describing what was changed
+func (s *Settings) validate() []string {
+	var problems []string
+	d := defaultSettings()
+
+	volume := func(name string, v *float64) {
+		if *v < 0 || *v > 1 || math.IsNaN(*v) {
+			problems = append(problems, fmt.Sprintf("%s volume %g is not between 0 and 1", name, *v))
+			*v = min(max(*v, 0), 1)
+			if math.IsNaN(*v) {
+				*v = 1
+			}
+		}
+	}
+	volume("master", &s.Audio.Master)
+	for ch := range channelCount {
+		volume(strings.ToLower(channelNames[ch]), s.Audio.channelVolume(ch))
+	}
+
+	problems = append(problems, s.Controls.validate()...)
+
+	known := func(what string, value *string, names []string, fallback string) {
+		if !slices.Contains(names, *value) {
+			problems = append(problems, fmt.Sprintf("unknown %s %q, using %q", what, *value, fallback))
+			*value = fallback
+		}
+	}
+	known("palette", &s.Accessibility.Palette, paletteNames(), d.Accessibility.Palette)
+	known("window mode", &s.Display.WindowMode, windowModes, d.Display.WindowMode)
+	known("asset pack", &s.Display.AssetPack, packNames(), d.Display.AssetPack)
+	known("ruleset", &s.Gameplay.Ruleset, rulesetNames(), d.Gameplay.Ruleset)
+	known("difficulty", &s.Gameplay.Difficulty, difficultyNames(), d.Gameplay.Difficulty)
+
+	if s.Display.WindowWidth < screenWidth/4 || s.Display.WindowHeight < screenHeight/4 {
+		problems = append(problems, fmt.Sprintf("window size %dx%d is too small", s.Display.WindowWidth, s.Display.WindowHeight))
+		s.Display.WindowWidth, s.Display.WindowHeight = d.Display.WindowWidth, d.Display.WindowHeight
+	}
+	if s.Gameplay.Lives < minLives || s.Gameplay.Lives > maxLives {
+		problems = append(problems, fmt.Sprintf("lives %d is not between %d and %d", s.Gameplay.Lives, minLives, maxLives))
+		s.Gameplay.Lives = min(max(s.Gameplay.Lives, minLives), maxLives)
+	}
+	return problems
+}
+
+func saveSettings(s Settings) error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		settingsModified = info.ModTime()
+	}
+	return nil
+}
+
+// Apply every setting that takes effect straight away
+func (g *Game) applySettings() {
+	g.usePack()
+	g.applyPalette()
+	applyWindowMode()
+}
+
+// Save after a change made in game, logging rather than failing if it can't be
+func (g *Game) settingsChanged() {
+	g.applySettings()
+	if err := saveSettings(settings); err != nil {
+		log.Printf("failed to save settings: %v", err)
+	}
+}
+
+// How often to look for the settings file being edited while the game runs
+const settingsCheckInterval = 1.0
+
+var (
+	settingsModified  time.Time // When the settings file was last loaded or saved
+	settingsCheckTime float64
+)
+
+// Pick up changes made to the settings file outside the game
+func (g *Game) watchSettings(dt float64) {
+	settingsCheckTime += dt
+	if settingsCheckTime < settingsCheckInterval {
+		return
+	}
+	settingsCheckTime = 0
+
+	path, err := settingsPath()
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.ModTime().After(settingsModified) {
+		return
+	}
+	s, err := loadSettings()
+	if err != nil {
+		log.Printf("failed to reload settings: %v", err)
+		return
+	}
+	ruleset := settings.Gameplay.Ruleset
+	settings = s
+	if s.Gameplay.Ruleset != ruleset {
+		g.setRuleset(s.Gameplay.Ruleset)
+	}
+	g.applySettings()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDefaultSettingsAreValid(t *testing.T) {
+	s := defaultSettings()
+	if problems := s.validate(); len(problems) > 0 {
+		t.Errorf("default settings have problems: %v", problems)
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	d := defaultSettings()
+	tests := []struct {
+		name  string
+		edit  func(s *Settings)
+		check func(s *Settings) bool
+	}{
+		{"loud", func(s *Settings) { s.Audio.Master = 2 }, func(s *Settings) bool { return s.Audio.Master == 1 }},
+		{"negative", func(s *Settings) { s.Audio.Effects = -1 }, func(s *Settings) bool { return s.Audio.Effects == 0 }},
+		{"not a number", func(s *Settings) { s.Audio.Music = math.NaN() }, func(s *Settings) bool { return s.Audio.Music == 1 }},
+		{"palette", func(s *Settings) { s.Accessibility.Palette = "sepia" }, func(s *Settings) bool {
+			return s.Accessibility.Palette == d.Accessibility.Palette
+		}},
+		{"window mode", func(s *Settings) { s.Display.WindowMode = "maximised" }, func(s *Settings) bool {
+			return s.Display.WindowMode == d.Display.WindowMode
+		}},
+		{"ruleset", func(s *Settings) { s.Gameplay.Ruleset = "chess" }, func(s *Settings) bool {
+			return s.Gameplay.Ruleset == d.Gameplay.Ruleset
+		}},
+		{"difficulty", func(s *Settings) { s.Gameplay.Difficulty = "nightmare" }, func(s *Settings) bool {
+			return s.Gameplay.Difficulty == d.Gameplay.Difficulty
+		}},
+		{"tiny window", func(s *Settings) { s.Display.WindowWidth = 10 }, func(s *Settings) bool {
+			return s.Display.WindowWidth == d.Display.WindowWidth && s.Display.WindowHeight == d.Display.WindowHeight
+		}},
+		{"no lives", func(s *Settings) { s.Gameplay.Lives = 0 }, func(s *Settings) bool { return s.Gameplay.Lives == minLives }},
+		{"too many lives", func(s *Settings) { s.Gameplay.Lives = 99 }, func(s *Settings) bool { return s.Gameplay.Lives == maxLives }},
+		{"unknown key", func(s *Settings) { s.Controls.Up = []string{"NoSuchKey", "W"} }, func(s *Settings) bool {
+			return len(s.Controls.Up) == 1 && s.Controls.Up[0] == "W"
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := defaultSettings()
+			test.edit(&s)
+			problems := s.validate()
+			if len(problems) != 1 {
+				t.Errorf("problems %q, want one", problems)
+			}
+			if !test.check(&s) {
+				t.Errorf("not put right: %+v", s)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"sync"
+)
+
+// Sounds play on channels. A channel plays one sound at a time and has its
+// own volume, under the master volume.
+type channel int
+
+const (
+	channelMusic channel = iota
+	channelSiren
+	channelEffects
+	channelJingles
+	channelCount
+)
+
+var channelNames = [channelCount]string{"Music", "Siren", "Effects", "Jingles"}
+
+// Seconds to fade from one sound to the next on channels that crossfade,
+// rather than cutting straight over
+var channelCrossfade = [channelCount]float64{channelSiren: 0.5}
+
+// Things that happen in the game that make a sound
+type soundEvent int
+
+const (
+	soundIntro soundEvent = iota
+	soundSiren            // First stage, with the most dots left
+	soundSiren2
+	soundSiren3
+	soundSiren4
+	soundSiren5
+	soundDot
+	soundDeath
+	soundGameOver
+	soundLevelComplete
+)
+
+// How a sound plays. A sound only takes over a busy channel from one of the
+// same or lower priority, and while it plays the channels it silences are
+// turned right down. Sounds with more than one voice can play over
+// themselves. Pitch speeds playback up (or slows it down), so one file can
+// make several sounds. Synth names the synthesised sound played instead of
+// the file when it's missing or in chiptune mode.
+type soundDef struct {
+	file     string
+	synth    string
+	channel  channel
+	priority int
+	voices   int
+	pitch    float64 // 1 if 0
+	loop     bool
+	silences []channel
+}
+
+// What every event sounds like
+var sounds = map[soundEvent]soundDef{
+	soundIntro:         {file: audioDir + "intro.wav", synth: "intro", channel: channelMusic, silences: []channel{channelSiren}},
+	soundSiren:         {file: audioDir + "siren.wav", synth: "siren", channel: channelSiren, loop: true},
+	soundSiren2:        {file: audioDir + "siren.wav", synth: "siren", channel: channelSiren, loop: true, pitch: 1.1},
+	soundSiren3:        {file: audioDir + "siren.wav", synth: "siren", channel: channelSiren, loop: true, pitch: 1.2},
+	soundSiren4:        {file: audioDir + "siren.wav", synth: "siren", channel: channelSiren, loop: true, pitch: 1.3},
+	soundSiren5:        {file: audioDir + "siren.wav", synth: "siren", channel: channelSiren, loop: true, pitch: 1.4},
+	soundDot:           {file: audioDir + "dot.wav", synth: "waka", channel: channelEffects, voices: 4},
+	soundDeath:         {file: audioDir + "gameover.wav", synth: "death", channel: channelJingles, priority: 2, silences: []channel{channelSiren, channelEffects}},
+	soundGameOver:      {file: audioDir + "gameover.wav", synth: "death", channel: channelJingles, priority: 2, silences: []channel{channelSiren, channelEffects}},
+	soundLevelComplete: {file: audioDir + "intermission.wav", synth: "jingle", channel: channelJingles, priority: 1, silences: []channel{channelSiren, channelEffects}},
+}
+
+// The siren stages, in order as the dots run out
+var sirenStages = []soundEvent{soundSiren, soundSiren2, soundSiren3, soundSiren4, soundSiren5}
+
+// What a channel is playing. Gain is how far it's faded in, or out.
+type channelSound struct {
+	event soundEvent
+	pool  *voicePool
+	gain  float64
+}
+
+// soundManager owns every audio player. The game asks it to play events
+// rather than handling players itself.
+type soundManager struct {
+	backend  audioBackend
+	pools    map[soundEvent]*voicePool // Created on first use
+	failed   map[string]bool           // Files that wouldn't load, so they're only reported once
+	playing  [channelCount]*channelSound
+	fading   []*channelSound // Sounds fading out under the one replacing them
+	paused   []audioPlayer   // To carry on with when unpausing
+	silent   bool            // Everything at zero volume, for the demo
+	chiptune bool            // Whether the pools were made in chiptune mode
+	mu       sync.Mutex
+}
+
+func newSoundManager(backend audioBackend) *soundManager {
+	return &soundManager{
+		backend: backend,
+		pools:   make(map[soundEvent]*voicePool),
+		failed:  make(map[string]bool),
+	}
+}
+
+func (s *soundManager) pool(event soundEvent) *voicePool {
+	pool, ok := s.pools[event]
+	if !ok {
+		pool = &voicePool{def: sounds[event], backend: s.backend, chiptune: s.chiptune}
+		s.pools[event] = pool
+	}
+	return pool
+}
+
+// Play the sound for an event from the start, unless it's already playing
+// and can't play over itself, or something more important has its channel
+func (s *soundManager) play(event soundEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	def := sounds[event]
+	if s.failed[def.file] {
+		return
+	}
+	gain := 1.0
+	if current := s.playing[def.channel]; current != nil && current.pool.playing() {
+		switch {
+		case current.event == event:
+			if def.maxVoices() == 1 {
+				return
+			}
+		case sounds[current.event].priority > def.priority:
+			return
+		case channelCrossfade[def.channel] > 0:
+			s.fading = append(s.fading, current)
+			gain = 0
+		default:
+			current.pool.pause()
+		}
+	}
+	pool := s.pool(event)
+	if i := slices.IndexFunc(s.fading, func(f *channelSound) bool { return f.pool == pool }); i >= 0 {
+		// Back before it had faded out, so it fades in again from there
+		// rather than being faded out and cut off under itself
+		s.playing[def.channel] = &channelSound{event: event, pool: pool, gain: s.fading[i].gain}
+		s.fading = slices.Delete(s.fading, i, i+1)
+		return
+	}
+	p, err := pool.voice()
+	if err != nil {
+		log.Printf("failed to load sound: %v", err)
+		s.failed[def.file] = true
+		return
+	}
+	s.playing[def.channel] = &channelSound{event: event, pool: pool, gain: gain}
+	p.SetVolume(s.volume(def.channel) * gain)
+	p.Rewind()
+	p.Play()
+}
+
+// Whether the event's sound is still playing
+func (s *soundManager) isPlaying(event soundEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := s.playing[sounds[event].channel]
+	return current != nil && current.event == event && current.pool.playing()
+}
+
+func (s *soundManager) stopChannel(ch channel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current := s.playing[ch]; current != nil {
+		current.pool.pause()
+		s.playing[ch] = nil
+	}
+	s.fading = slices.DeleteFunc(s.fading, func(f *channelSound) bool {
+		if sounds[f.event].channel == ch {
+			f.pool.pause()
+			return true
+		}
+		return false
+	})
+}
+
+// Pause whatever is playing, remembering it to carry on with later
+func (s *soundManager) pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, current := range s.playing {
+		if current != nil {
+			s.paused = append(s.paused, current.pool.pause()...)
+		}
+	}
+	// Fades aren't worth carrying on with
+	for _, f := range s.fading {
+		f.pool.pause()
+	}
+	s.fading = nil
+	for _, current := range s.playing {
+		if current != nil {
+			current.gain = 1
+		}
+	}
+}
+
+func (s *soundManager) resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.paused {
+		p.Play()
+	}
+	s.paused = nil
+}
+
+// Stop everything for good, e.g. when leaving the game
+func (s *soundManager) stop() {
+	s.pause()
+	s.paused = nil
+}
+
+// How loud a channel should be right now
+func (s *soundManager) volume(ch channel) float64 {
+	a := &settings.Audio
+	if a.Muted || s.silent {
+		return 0
+	}
+	for _, current := range s.playing {
+		if current != nil && current.pool.playing() && slices.Contains(sounds[current.event].silences, ch) {
+			return 0
+		}
+	}
+	return a.Master * *a.channelVolume(ch)
+}
+
+// Bring every channel to its volume, after settings change or a sound starts
+// or stops silencing others, and move crossfades along. Called every frame.
+func (s *soundManager) update(dt float64) {
+	if settings.Audio.Chiptune != s.chiptune {
+		s.reload()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch, current := range s.playing {
+		if current == nil {
+			continue
+		}
+		if fade := channelCrossfade[ch]; fade > 0 {
+			current.gain = min(current.gain+dt/fade, 1)
+		}
+		current.pool.setVolume(s.volume(channel(ch)) * current.gain)
+	}
+	s.fading = slices.DeleteFunc(s.fading, func(f *channelSound) bool {
+		ch := sounds[f.event].channel
+		f.gain -= dt / channelCrossfade[ch]
+		if f.gain <= 0 {
+			f.pool.pause()
+			return true
+		}
+		f.pool.setVolume(s.volume(ch) * f.gain)
+		return false
+	})
+}
+
+// Close every player so sounds are made again, from the current asset
+// pack's files or the synthesiser as chiptune mode now says. Whatever was
+// playing stops.
+func (s *soundManager) reload() {
+	s.stop()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, pool := range s.pools {
+		pool.close()
+	}
+	s.chiptune = settings.Audio.Chiptune
+	s.pools = make(map[soundEvent]*voicePool)
+	s.failed = make(map[string]bool)
+	s.playing = [channelCount]*channelSound{}
+}
+
+// The mute keys turn all sound off and on
+func (g *Game) handleSoundKeys() {
+	if keyJustPressed(settings.Controls.Mute) {
+		settings.Audio.Muted = !settings.Audio.Muted
+		g.settingsChanged()
+	}
+}
+
+// The background siren's stage, which rises as the dots run out
+func (g *Game) sirenSound() soundEvent {
+	eaten := 0.0
+	if g.dotsAtStart > 0 {
+		eaten = 1 - float64(len(Dots))/float64(g.dotsAtStart)
+	}
+	stage := min(int(eaten*float64(len(sirenStages))), len(sirenStages)-1)
+	return sirenStages[max(stage, 0)]
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Side of a spatial grid cell. A Pacman-sized box overlaps at most four.
+const gridCellSize = 4 * tileSize
+
+// spatialGrid buckets walls and dots by the cells they cover, so collision
+// checks and dot pickup only look at what's nearby rather than the whole maze.
+// Buckets hold indices into g.walls and Dots.
+type spatialGrid struct {
+	cols, rows int
+	walls      [][]int
+	dots       [][]int
+}
+
+func newSpatialGrid(width, height float64, walls []Wall) *spatialGrid {
+	s := &spatialGrid{
+		cols: int(math.Ceil(width / gridCellSize)),
+		rows: int(math.Ceil(height / gridCellSize)),
+	}
+	s.walls = make([][]int, s.cols*s.rows)
+	for i, w := range walls {
+		s.each(w.x, w.y, w.x+w.Width, w.y+w.Height, func(cell int) {
+			s.walls[cell] = append(s.walls[cell], i)
+		})
+	}
+	return s
+}
+
+// Call fn for every cell overlapping the box, skipping any part of it off the grid
+func (s *spatialGrid) each(x0, y0, x1, y1 float64, fn func(cell int)) {
+	c0, r0 := max(int(math.Floor(x0/gridCellSize)), 0), max(int(math.Floor(y0/gridCellSize)), 0)
+	c1, r1 := min(int(math.Floor(x1/gridCellSize)), s.cols-1), min(int(math.Floor(y1/gridCellSize)), s.rows-1)
+	for row := r0; row <= r1; row++ {
+		for col := c0; col <= c1; col++ {
+			fn(row*s.cols + col)
+		}
+	}
+}
+
+// Whether found returns true for any item in a cell overlapping the box.
+// Walls covering several cells may be passed more than once.
+func (s *spatialGrid) search(buckets [][]int, x0, y0, x1, y1 float64, found func(i int) bool) bool {
+	hit := false
+	s.each(x0, y0, x1, y1, func(cell int) {
+		for _, i := range buckets[cell] {
+			if !hit && found(i) {
+				hit = true
+			}
+		}
+	})
+	return hit
+}
+
+func (s *spatialGrid) cellOf(x, y float64) int {
+	col := min(max(int(x/gridCellSize), 0), s.cols-1)
+	row := min(max(int(y/gridCellSize), 0), s.rows-1)
+	return row*s.cols + col
+}
+
+// Re-bucket every dot, after Dots is replaced
+func (s *spatialGrid) indexDots(dots []Dot) {
+	s.dots = make([][]int, s.cols*s.rows)
+	for i, dot := range dots {
+		cell := s.cellOf(dot.x, dot.y)
+		s.dots[cell] = append(s.dots[cell], i)
+	}
+}
+
+// Indices of the dots within r of x, y, highest first so they can be removed in order
+func (s *spatialGrid) dotsWithin(dots []Dot, x, y, r float64) []int {
+	var near []int
+	s.search(s.dots, x-r, y-r, x+r, y+r, func(i int) bool {
+		if distance(x, y, dots[i].x, dots[i].y) < r {
+			near = append(near, i)
+		}
+		return false
+	})
+	sort.Sort(sort.Reverse(sort.IntSlice(near)))
+	return near
+}
+
+// Take dot i out of Dots. The last dot moves into its place, so only that
+// one's bucket needs fixing up.
+func (g *Game) removeDot(i int) {
+	s := g.grid
+	last := len(Dots) - 1
+	cell := s.cellOf(Dots[i].x, Dots[i].y)
+	for k, j := range s.dots[cell] {
+		if j == i {
+			s.dots[cell] = append(s.dots[cell][:k], s.dots[cell][k+1:]...)
+			break
+		}
+	}
+	if i != last {
+		cell = s.cellOf(Dots[last].x, Dots[last].y)
+		for k, j := range s.dots[cell] {
+			if j == last {
+				s.dots[cell][k] = i
+				break
+			}
+		}
+		Dots[i] = Dots[last]
+	}
+	Dots = Dots[:last]
+}
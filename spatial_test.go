@@ -0,0 +1,69 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// Every dot within r of x, y by checking them all, highest index first
+func dotsWithinSlowly(x, y, r float64) []int {
+	var near []int
+	for i, dot := range Dots {
+		if distance(x, y, dot.x, dot.y) < r {
+			near = append(near, i)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(near)))
+	return near
+}
+
+func TestDotsWithin(t *testing.T) {
+	defer func(dots []Dot) { Dots = dots }(Dots)
+	r := rand.New(rand.NewSource(1))
+	g := &Game{grid: newSpatialGrid(screenWidth, screenHeight, nil)}
+	Dots = nil
+	for range 300 {
+		Dots = append(Dots, Dot{x: r.Float64() * screenWidth, y: r.Float64() * screenHeight})
+	}
+	g.grid.indexDots(Dots)
+
+	for range 50 {
+		x, y := r.Float64()*screenWidth, r.Float64()*screenHeight
+		radius := r.Float64() * 5 * tileSize
+		got, want := g.grid.dotsWithin(Dots, x, y, radius), dotsWithinSlowly(x, y, radius)
+		if !slices.Equal(got, want) {
+			t.Fatalf("dots within %g of (%g, %g) are %v, want %v", radius, x, y, got, want)
+		}
+	}
+}
+
+func TestRemoveDot(t *testing.T) {
+	defer func(dots []Dot) { Dots = dots }(Dots)
+	r := rand.New(rand.NewSource(2))
+	g := &Game{grid: newSpatialGrid(screenWidth, screenHeight, nil)}
+	Dots = nil
+	for range 200 {
+		Dots = append(Dots, Dot{x: r.Float64() * screenWidth, y: r.Float64() * screenHeight})
+	}
+	g.grid.indexDots(Dots)
+
+	// Eat them the way Pacman does, checking the index stays right
+	for len(Dots) > 0 {
+		x, y := r.Float64()*screenWidth, r.Float64()*screenHeight
+		for _, i := range g.grid.dotsWithin(Dots, x, y, 8*tileSize) {
+			g.removeDot(i)
+		}
+		buckets := 0
+		for _, bucket := range g.grid.dots {
+			buckets += len(bucket)
+		}
+		if buckets != len(Dots) {
+			t.Fatalf("%d dots indexed, %d left", buckets, len(Dots))
+		}
+		if got, want := g.grid.dotsWithin(Dots, x, y, screenWidth), dotsWithinSlowly(x, y, screenWidth); !slices.Equal(got, want) {
+			t.Fatalf("after removing, dots are %v, want %v", got, want)
+		}
+	}
+}
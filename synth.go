@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sync"
+)
+
+// A tiny synthesiser, standing in for sound files that are missing and
+// playing everything in chiptune mode. Sounds come out as 16-bit stereo PCM
+// at sampleRate, the same as a decoded file.
+
+// A note sliding from one frequency to another. A frequency of 0 is a rest.
+type tone struct {
+	from, to float64
+	seconds  float64
+}
+
+// A waveform gives the level, -1 to 1, at a point through one cycle
+type waveform func(phase float64) float64
+
+func square(phase float64) float64 {
+	if phase < 0.5 {
+		return 1
+	}
+	return -1
+}
+
+func triangle(phase float64) float64 {
+	return 1 - 4*math.Abs(phase-0.5)
+}
+
+// Frequency of a MIDI note number
+func midi(note int) float64 {
+	return 440 * math.Pow(2, float64(note-69)/12)
+}
+
+// A tune as notes of the same length, 0 for a rest
+func melody(seconds float64, notes ...int) []tone {
+	return Map(notes, func(n int) tone {
+		if n == 0 {
+			return tone{seconds: seconds}
+		}
+		return tone{from: midi(n), to: midi(n), seconds: seconds}
+	})
+}
+
+// Samples to fade each note in and out over, so notes don't click
+const synthFade = sampleRate / 500
+
+// Render tones one after another. Loops keep their phase running across
+// notes and skip the fades, so they join up without a click.
+func render(wave waveform, volume float64, loop bool, tones ...tone) []byte {
+	var out []byte
+	phase := 0.0
+	for _, t := range tones {
+		n := int(t.seconds * sampleRate)
+		for i := range n {
+			f := t.from + (t.to-t.from)*float64(i)/float64(n)
+			phase = math.Mod(phase+f/sampleRate, 1)
+			v := 0.0
+			if f > 0 {
+				v = wave(phase) * volume
+			}
+			if !loop {
+				v *= min(1, float64(i)/synthFade, float64(n-i)/synthFade)
+			}
+			sample := uint16(int16(v * math.MaxInt16))
+			out = binary.LittleEndian.AppendUint16(out, sample) // Left
+			out = binary.LittleEndian.AppendUint16(out, sample) // Right
+		}
+	}
+	return out
+}
+
+// Everything the synthesiser can play, by name
+var synthSounds = map[string]func() []byte{
+	"waka": func() []byte {
+		return render(triangle, 0.5, false, tone{250, 500, 0.06}, tone{500, 250, 0.06})
+	},
+	"siren": func() []byte {
+		return render(triangle, 0.3, true, tone{500, 900, 0.2}, tone{900, 500, 0.2})
+	},
+	"death": func() []byte {
+		return render(square, 0.25, false, tone{900, 150, 1.2}, tone{seconds: 0.1},
+			tone{200, 100, 0.1}, tone{seconds: 0.05}, tone{200, 100, 0.1})
+	},
+	"intro": func() []byte {
+		return render(square, 0.2, false, melody(0.12,
+			71, 83, 78, 75, 83, 78, 75, 0,
+			72, 84, 79, 76, 84, 79, 76, 0,
+			71, 83, 78, 75, 83, 78, 75, 0,
+			75, 76, 77, 0, 77, 78, 79, 0, 79, 80, 81, 83)...)
+	},
+	"jingle": func() []byte {
+		return render(square, 0.2, false, melody(0.1,
+			72, 76, 79, 84, 0, 79, 84, 0, 76, 79, 84, 88)...)
+	},
+}
+
+var (
+	synthesised   = make(map[string][]byte)
+	synthesisedMu sync.Mutex
+)
+
+// pcmStream plays synthesised samples
+type pcmStream struct {
+	*bytes.Reader
+}
+
+func (p pcmStream) Length() int64 {
+	return p.Size()
+}
+
+// A stream of the named synthesised sound, rendered the first time it's asked for
+func synthesise(name string) (audioStream, bool) {
+	render, ok := synthSounds[name]
+	if !ok {
+		return nil, false
+	}
+	synthesisedMu.Lock()
+	defer synthesisedMu.Unlock()
+	data, ok := synthesised[name]
+	if !ok {
+		data = render()
+		synthesised[name] = data
+	}
+	return pcmStream{bytes.NewReader(data)}, true
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// The simulation runs in fixed steps whatever the frame rate, so the game
+// plays the same on any machine. Frames carry leftover time over to the next
+// one, and drawing blends between the last two steps.
+const (
+	simRate      = 60            // Steps per second
+	simStep      = 1.0 / simRate // Seconds per step
+	maxFrameTime = 0.25          // Longest frame caught up on, so a stall doesn't snowball
+
+	// Anything moving further than this in one step jumped, through a tunnel
+	// or teleporter, and isn't blended
+	maxBlendDistance = 4 * tileSize
+)
+
+// Where things were as of the previous step
+type snapshot struct {
+	pacman Point
+	ghosts []Point
+	camera Camera
+}
+
+// Seconds since the last frame, capped at maxFrameTime
+func (g *Game) frameTime() float64 {
+	now := time.Now()
+	dt := 0.0
+	if !g.lastFrame.IsZero() {
+		dt = math.Min(now.Sub(g.lastFrame).Seconds(), maxFrameTime)
+	}
+	g.lastFrame = now
+	return dt
+}
+
+// Run as many steps as the frame's time covers
+func (g *Game) advance(dt float64) {
+	g.accumulator += dt
+	for g.accumulator >= simStep {
+		g.previous = g.snapshot()
+		g.step()
+		// Virtual audio keeps time with the simulation, so sounds end on
+		// the same step every run
+		g.sounds.backend.advance(simStep)
+		g.accumulator -= simStep
+	}
+}
+
+func (g *Game) snapshot() snapshot {
+	return snapshot{
+		pacman: Point{x: g.pacman.x, y: g.pacman.y},
+		ghosts: Map(g.ghost, func(p Pacman) Point { return Point{x: p.x, y: p.y} }),
+		camera: g.camera,
+	}
+}
+
+// What a frame draws: copies of everything that moves, so drawing never
+// touches the simulation
+type drawState struct {
+	pacman Pacman
+	ghosts []Pacman
+	camera Camera
+}
+
+// Everything as it is after the last step
+func (g *Game) currentDrawState() drawState {
+	return drawState{pacman: g.pacman, ghosts: slices.Clone(g.ghost), camera: g.camera}
+}
+
+// Everything part way back towards the previous step, alpha being how far
+// through the current step we are
+func (g *Game) interpolate(alpha float64) drawState {
+	d := g.currentDrawState()
+	blend := func(from Point, x, y *float64) {
+		if distance(from.x, from.y, *x, *y) > maxBlendDistance {
+			return
+		}
+		*x, *y = from.x+(*x-from.x)*alpha, from.y+(*y-from.y)*alpha
+	}
+
+	blend(g.previous.pacman, &d.pacman.x, &d.pacman.y)
+	if len(g.previous.ghosts) == len(d.ghosts) {
+		for i := range d.ghosts {
+			blend(g.previous.ghosts[i], &d.ghosts[i].x, &d.ghosts[i].y)
+		}
+	}
+	blend(Point(g.previous.camera), &d.camera.x, &d.camera.y)
+	return d
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// A game with a never-ending virtual sound, whose position counts the steps
+// run, since the virtual clock moves on once a step
+func newCountingGame(t *testing.T) (*Game, func() int) {
+	t.Helper()
+	g := newTestGame(t)
+	clock, err := g.sounds.backend.newPlayer(nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Play()
+	return g, func() int {
+		return int(math.Round(clock.(*virtualPlayer).position / simStep))
+	}
+}
+
+func TestAdvanceCarriesLeftoverTime(t *testing.T) {
+	g, steps := newCountingGame(t)
+	g.advance(2.5 * simStep)
+	if steps() != 2 {
+		t.Fatalf("%d steps for two and a half steps' time", steps())
+	}
+	if math.Abs(g.accumulator-simStep/2) > 1e-9 {
+		t.Errorf("%g seconds carried over, want half a step", g.accumulator)
+	}
+	g.advance(0.75 * simStep)
+	if steps() != 3 {
+		t.Errorf("%d steps once the carried over time made up a step", steps())
+	}
+	if math.Abs(g.accumulator-simStep/4) > 1e-9 {
+		t.Errorf("%g seconds carried over, want a quarter of a step", g.accumulator)
+	}
+}
+
+func TestAdvanceKeepsTimeAtAnyFrameRate(t *testing.T) {
+	for _, fps := range []int{30, 60, 144, 240} {
+		g, steps := newCountingGame(t)
+		for range fps {
+			g.advance(1 / float64(fps))
+		}
+		// However the frames fall, a second of them is a second of steps,
+		// give or take the time still carried over
+		if got := float64(steps())*simStep + g.accumulator; math.Abs(got-1) > 1e-9 {
+			t.Errorf("%d fps: %d steps and %gs carried over for a second", fps, steps(), g.accumulator)
+		}
+		if g.accumulator < 0 || g.accumulator >= simStep {
+			t.Errorf("%d fps: %gs carried over, more than a step", fps, g.accumulator)
+		}
+	}
+}
+
+func TestFrameTimeIsCapped(t *testing.T) {
+	g := newTestGame(t)
+	g.lastFrame = time.Now().Add(-time.Second)
+	if dt := g.frameTime(); dt != maxFrameTime {
+		t.Errorf("a one second stall gave a %gs frame, want %gs", dt, maxFrameTime)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	g := newTestGame(t)
+	g.previous = g.snapshot()
+	g.pacman.x += tileSize
+	halfway := g.previous.pacman.x + tileSize/2.0
+	if d := g.interpolate(0.5); d.pacman.x != halfway {
+		t.Errorf("pacman drawn at x %g half way through a step, want %g", d.pacman.x, halfway)
+	}
+	if d := g.interpolate(1); d.pacman.x != g.pacman.x {
+		t.Errorf("pacman drawn at x %g at the end of a step, want %g", d.pacman.x, g.pacman.x)
+	}
+
+	// Going through a tunnel jumps, so isn't blended
+	g.pacman.x += 2 * maxBlendDistance
+	if d := g.interpolate(0.5); d.pacman.x != g.pacman.x {
+		t.Errorf("pacman blended across a jump, drawn at x %g", d.pacman.x)
+	}
+}
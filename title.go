@@ -0,0 +1,123 @@
+package main
+
+import (
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+const (
+	attractDelay   = 15.0 // Seconds idle on the title screen before the demo starts
+	demoLength     = 60.0 // Seconds the demo runs before going back to the title
+	gameOverLength = 3.0  // Seconds GAME OVER shows before going back to the title
+)
+
+func (g *Game) showTitle() {
+	g.sounds.stop()
+	g.onTitle = true
+	g.idleTime = 0
+	g.menus = []*menu{g.titleMenu()}
+}
+
+func (g *Game) titleMenu() *menu {
+	return &menu{
+		title: "PAC-MAN",
+		items: []menuItem{
+			{label: "Start", activate: g.start},
+			{label: "Mode", value: func() string { return g.mode.String() }, adjust: func(delta int) {
+				g.mode = GameMode((int(g.mode) + delta + len(gameModeNames)) % len(gameModeNames))
+			}},
+			{label: "High scores", activate: func() { g.openMenu(g.highScoresMenu()) }},
+			{label: "Options", activate: func() { g.openMenu(g.optionsMenu()) }},
+			{label: "Quit", activate: func() { g.quitting = true }},
+		},
+	}
+}
+
+// Leave the title screen for a new game in the chosen mode
+func (g *Game) start() {
+	if g.mode == modeEndless && g.seed == 0 {
+		g.seed = time.Now().UnixNano()
+	}
+	g.onTitle = false
+	g.menus = nil
+	g.restart()
+}
+
+// Whether any key or gamepad button went down this frame
+func anyInput() bool {
+	if len(inpututil.AppendJustPressedKeys(nil)) > 0 {
+		return true
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if len(inpututil.AppendJustPressedStandardGamepadButtons(id, nil)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Count idle time on the title screen, starting the demo when it's been long enough
+func (g *Game) updateTitle(dt float64) {
+	g.idleTime += dt
+	if anyInput() {
+		g.idleTime = 0
+	}
+	if g.idleTime >= attractDelay && len(g.menus) == 1 {
+		g.startDemo()
+	}
+}
+
+// The attract loop: the game plays itself, silently, on the classic maze
+func (g *Game) startDemo() {
+	g.demo = true
+	g.demoTime = 0
+	g.onTitle = false
+	g.menus = nil
+	g.sounds.silent = true
+	mode := g.mode
+	g.mode = modeClassic
+	g.restart()
+	g.mode = mode
+}
+
+func (g *Game) updateDemo(dt float64) {
+	g.demoTime += dt
+	if anyInput() || g.demoTime >= demoLength || g.gameOverState {
+		g.demo = false
+		g.sounds.silent = false
+		g.showTitle()
+		return
+	}
+	g.advance(dt)
+}
+
+// Steer for the nearest dot along the maze, keeping to the lanes
+func (g *Game) demoDirection() Direction {
+	from, ok := g.nav.nearestOpen(g.pacman.x, g.pacman.y)
+	if !ok || len(Dots) == 0 {
+		return None
+	}
+	nearest := Dots[0]
+	for _, dot := range Dots {
+		if distance(g.pacman.x, g.pacman.y, dot.x, dot.y) < distance(g.pacman.x, g.pacman.y, nearest.x, nearest.y) {
+			nearest = dot
+		}
+	}
+	to, ok := g.nav.nearestOpen(nearest.x, nearest.y)
+	if !ok {
+		return None
+	}
+	b, ok := g.nav.next(from, to)
+	if !ok {
+		return None
+	}
+	a := from
+	for d, v := range directionVectors {
+		if g.laneNode(g.nav.centre(a).x+v.x*g.nav.step, g.nav.centre(a).y+v.y*g.nav.step) == b {
+			return d
+		}
+	}
+	return None
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Tunables are the numbers the game's feel comes down to. They're fixed
+// normally, but in dev mode they're read from tunablesPath and reloaded as
+// it's edited.
+type Tunables struct {
+	PacmanSpeed   float64 `json:"pacmanSpeed"` // Pixels per second
+	GhostSpeed    float64 `json:"ghostSpeed"`  // Pixels per second on the first level
+	PacmanRadius  float64 `json:"pacmanRadius"`
+	GhostRadius   float64 `json:"ghostRadius"`
+	DotRadius     float64 `json:"dotRadius"`
+	PelletRadius  float64 `json:"pelletRadius"`
+	MinSeparation float64 `json:"minSeparation"` // Ghosts closer than this push apart
+	LookAhead     float64 `json:"lookAhead"`     // Steps ahead ghosts check for walls
+}
+
+const tunablesFile = "tunables.json"
+
+// Where dev mode reads the tunables from: the -tunables flag, or the config
+// directory. Empty if there's nowhere to keep them.
+var tunablesPath string
+
+func defaultTunablesPath() string {
+	dir, err := configDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, tunablesFile)
+}
+
+var tunables = defaultTunables()
+
+func defaultTunables() Tunables {
+	return Tunables{
+		PacmanSpeed:   pacmanSpeed,
+		GhostSpeed:    ghostSpeed,
+		PacmanRadius:  pacmanRadius,
+		GhostRadius:   pacmanRadius,
+		DotRadius:     dotRadius,
+		PelletRadius:  pelletRadius,
+		MinSeparation: 30,
+		LookAhead:     3,
+	}
+}
+
+// Read tunables from path, on top of the defaults so it only needs the ones
+// being changed. A missing file is written out with the defaults to edit.
+// A file that won't parse or has bad values gives the defaults, never part
+// of what it says.
+func loadTunables(path string) (Tunables, error) {
+	t := defaultTunables()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = json.MarshalIndent(t, "", "  ")
+		if err != nil {
+			return t, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return t, err
+		}
+		return t, os.WriteFile(path, append(data, '\n'), 0o644)
+	}
+	if err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return defaultTunables(), fmt.Errorf("%s: %w", path, err)
+	}
+	if err := t.validate(); err != nil {
+		return defaultTunables(), fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+func (t Tunables) validate() error {
+	for _, v := range []struct {
+		name  string
+		value float64
+	}{
+		{"pacmanSpeed", t.PacmanSpeed}, {"ghostSpeed", t.GhostSpeed},
+		{"pacmanRadius", t.PacmanRadius}, {"ghostRadius", t.GhostRadius},
+		{"dotRadius", t.DotRadius}, {"pelletRadius", t.PelletRadius},
+		{"lookAhead", t.LookAhead},
+	} {
+		if v.value <= 0 {
+			return fmt.Errorf("%s must be more than 0", v.name)
+		}
+	}
+	if t.MinSeparation < 0 {
+		return errors.New("minSeparation can't be negative")
+	}
+	return nil
+}
+
+// Use new tunables for everything made from now on
+func useTunables(t Tunables) {
+	tunables = t
+	for i := range Ghost {
+		Ghost[i].radius = t.GhostRadius
+		Ghost[i].speed = t.GhostSpeed
+	}
+}
+
+// Use new tunables, changing everything already in play to match. Ghosts
+// keep whatever they've sped up by since the first level.
+func (g *Game) applyTunables(t Tunables) {
+	old := tunables
+	useTunables(t)
+	for i := range g.ghost {
+		g.ghost[i].radius = t.GhostRadius
+		g.ghost[i].speed += t.GhostSpeed - old.GhostSpeed
+	}
+	g.pacman.radius = t.PacmanRadius
+	// Which nodes are open depends on the radius, and so do the distances
+	// cached between them, so start again with a fresh grid
+	if t.PacmanRadius != old.PacmanRadius || t.GhostRadius != old.GhostRadius {
+		g.nav = g.buildNavGrid(tileSize)
+	}
+	for i := range Dots {
+		if Dots[i].power {
+			Dots[i].radius = t.PelletRadius
+		} else {
+			Dots[i].radius = t.DotRadius
+		}
+	}
+}
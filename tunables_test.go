@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Count the nodes a body can stand on
+func openNodes(n *navGrid) int {
+	count := 0
+	for _, open := range n.open {
+		if open {
+			count++
+		}
+	}
+	return count
+}
+
+func TestApplyTunablesRebuildsNav(t *testing.T) {
+	g := newTestGame(t)
+	defer useTunables(defaultTunables())
+	g.nav.distancesTo(0)
+	before := openNodes(g.nav)
+
+	bigger := defaultTunables()
+	bigger.PacmanRadius *= 1.5
+	bigger.GhostRadius *= 1.5
+	g.applyTunables(bigger)
+	if after := openNodes(g.nav); after >= before {
+		t.Errorf("%d nodes open with a bigger radius, %d before", after, before)
+	}
+	if g.nav.distances.Len() > 0 {
+		t.Errorf("%d distances still cached after the radius changed", g.nav.distances.Len())
+	}
+}
+
+func TestLoadTunablesFallsBack(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"broken json", `{"pacmanSpeed": 90,`},
+		{"wrong type", `{"pacmanSpeed": "fast"}`},
+		{"zero radius", `{"pacmanSpeed": 90, "pacmanRadius": 0}`},
+		{"negative separation", `{"pacmanSpeed": 90, "minSeparation": -1}`},
+	}
+	for _, test := range tests {
+		path := filepath.Join(t.TempDir(), tunablesFile)
+		if err := os.WriteFile(path, []byte(test.file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := loadTunables(path)
+		if err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+		// None of the file is used, not even the good values
+		if got != defaultTunables() {
+			t.Errorf("%s: got %+v, want the defaults", test.name, got)
+		}
+	}
+}
+
+func TestLoadTunables(t *testing.T) {
+	dir := t.TempDir()
+
+	// A missing file is written out with the defaults
+	path := filepath.Join(dir, "new", tunablesFile)
+	if got, err := loadTunables(path); err != nil || got != defaultTunables() {
+		t.Fatalf("missing file gave %+v, %v", got, err)
+	}
+	var written Tunables
+	if data, err := os.ReadFile(path); err != nil || json.Unmarshal(data, &written) != nil || written != defaultTunables() {
+		t.Errorf("defaults weren't written out: %v", err)
+	}
+
+	// A file only needs the values being changed
+	path = filepath.Join(dir, tunablesFile)
+	if err := os.WriteFile(path, []byte(`{"pacmanSpeed": 90}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want := defaultTunables()
+	want.PacmanSpeed = 90
+	if got, err := loadTunables(path); err != nil || got != want {
+		t.Errorf("got %+v, %v, want %+v", got, err, want)
+	}
+}
+
+// A bad edit in dev mode leaves the tunables in play as they were
+func TestReloadBadTunables(t *testing.T) {
+	g := newTestGame(t)
+	defer useTunables(defaultTunables())
+	faster := defaultTunables()
+	faster.GhostSpeed *= 2
+	g.applyTunables(faster)
+
+	saved := tunablesPath
+	defer func() { tunablesPath = saved }()
+	tunablesPath = filepath.Join(t.TempDir(), tunablesFile)
+	if err := os.WriteFile(tunablesPath, []byte(`{"ghostSpeed": -1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.reloadTunables(); err == nil {
+		t.Error("no error reloading a bad file")
+	}
+	if tunables != faster || g.ghost[0].speed != faster.GhostSpeed {
+		t.Errorf("tunables changed to %+v", tunables)
+	}
+}
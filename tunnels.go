@@ -0,0 +1,104 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// Ghosts move at this fraction of their speed inside tunnels
+const tunnelSpeedFactor = 0.5
+
+// Teleporter moves anything entering one pad to the centre of the other
+type Teleporter struct {
+	A TileRect `json:"a"`
+	B TileRect `json:"b"`
+}
+
+type teleporter struct {
+	a, b Wall
+}
+
+func (w *Wall) contains(x, y float64) bool {
+	return x >= w.x && x < w.x+w.Width && y >= w.y && y < w.y+w.Height
+}
+
+func (w *Wall) centre() Point {
+	return Point{x: w.x + w.Width/2, y: w.y + w.Height/2}
+}
+
+func (g *Game) inTunnel(x, y float64) bool {
+	for _, tunnel := range g.tunnels {
+		if tunnel.contains(x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether an entity of radius r can be at x, y without leaving the world.
+// Tunnels are the only way past the edges.
+func (g *Game) inBounds(x, y, r float64) bool {
+	if x >= r && x <= g.worldWidth-r && y >= r && y <= g.worldHeight-r {
+		return true
+	}
+	return g.inTunnel(x, y) || g.inTunnel(x-g.worldWidth, y) || g.inTunnel(x+g.worldWidth, y) ||
+		g.inTunnel(x, y-g.worldHeight) || g.inTunnel(x, y+g.worldHeight)
+}
+
+// Wrap p around the world edges and through teleporters.
+// Returns true if p jumped somewhere else in the maze.
+func (g *Game) followLinks(p *Pacman) bool {
+	moved := false
+	switch {
+	case p.x < 0:
+		p.x += g.worldWidth
+		moved = true
+	case p.x >= g.worldWidth:
+		p.x -= g.worldWidth
+		moved = true
+	}
+	switch {
+	case p.y < 0:
+		p.y += g.worldHeight
+		moved = true
+	case p.y >= g.worldHeight:
+		p.y -= g.worldHeight
+		moved = true
+	}
+
+	// Only teleport on stepping onto a pad, so arriving on the other pad doesn't send it straight back
+	onPad := false
+	for _, t := range g.teleporters {
+		var exit Wall
+		switch {
+		case t.a.contains(p.x, p.y):
+			exit = t.b
+		case t.b.contains(p.x, p.y):
+			exit = t.a
+		default:
+			continue
+		}
+		onPad = true
+		if !p.onPad {
+			centre := exit.centre()
+			p.x, p.y = centre.x, centre.y
+			moved = true
+		}
+		break
+	}
+	p.onPad = onPad
+	return moved
+}
+
+func (g *Game) drawTeleporters(screen *ebiten.Image) {
+	for _, t := range g.teleporters {
+		for _, pad := range []Wall{t.a, t.b} {
+			c := pad.centre()
+			r := float32(min(pad.Width, pad.Height) / 2)
+			vector.StrokeCircle(screen, float32(c.x), float32(c.y), r, 2, color.White, false)
+			vector.StrokeCircle(screen, float32(c.x), float32(c.y), r/2, 2, currentPalette().Cage, false)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Result of validating a level. Errors make the level unplayable,
+// warnings are things a maze probably shouldn't have.
+type validationReport struct {
+	errors   []string
+	warnings []string
+}
+
+func (r *validationReport) errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *validationReport) warnf(format string, args ...any) {
+	r.warnings = append(r.warnings, fmt.Sprintf(format, args...))
+}
+
+// Problems that would stop a level from being playable
+func checkLevel(level *Level) []string {
+	var problems []string
+	r := tunables.PacmanRadius
+	g := &Game{pacman: Pacman{radius: r}}
+	g.loadMaze(level)
+
+	if !g.inBounds(g.spawn.x, g.spawn.y, r) || g.anyCollision(g.spawn.x, g.spawn.y, r) {
+		problems = append(problems, "pacman spawn is blocked")
+	}
+	if len(level.Ghosts) == 0 {
+		problems = append(problems, "no ghost spawns")
+	}
+	cage := level.Cage.wall()
+	for i, spawn := range level.Ghosts {
+		if p := spawn.point(); !cage.contains(p.x, p.y) {
+			problems = append(problems, fmt.Sprintf("ghost %d spawns outside the cage", i+1))
+		}
+	}
+	if g.anyCollision(g.ghostExit.x, g.ghostExit.y, tunables.GhostRadius) {
+		problems = append(problems, "ghost exit is blocked")
+	}
+
+	// A tunnel leads to the same rows or columns on the opposite edge
+	for i, tunnel := range level.Tunnels {
+		var opposite TileRect
+		switch {
+		case tunnel.Col <= 0:
+			opposite = TileRect{Col: float64(level.Cols) - 1, Row: tunnel.Row, Cols: 1, Rows: tunnel.Rows}
+		case tunnel.Col+tunnel.Cols >= float64(level.Cols):
+			opposite = TileRect{Col: 0, Row: tunnel.Row, Cols: 1, Rows: tunnel.Rows}
+		case tunnel.Row <= 0:
+			opposite = TileRect{Col: tunnel.Col, Row: float64(level.Rows) - 1, Cols: tunnel.Cols, Rows: 1}
+		case tunnel.Row+tunnel.Rows >= float64(level.Rows):
+			opposite = TileRect{Col: tunnel.Col, Row: 0, Cols: tunnel.Cols, Rows: 1}
+		default:
+			problems = append(problems, fmt.Sprintf("tunnel %d does not touch the edge of the maze", i+1))
+			continue
+		}
+		edge := opposite.wall()
+		if c := edge.centre(); !g.inTunnel(c.x, c.y) {
+			problems = append(problems, fmt.Sprintf("tunnel %d has no tunnel on the opposite edge", i+1))
+		}
+	}
+	return problems
+}
+
+// Check a level thoroughly: everything checkLevel does, then flood fill from
+// Pacman's spawn with the game's own collision rules to find dots Pacman can
+// never eat, whether the ghosts can get out of their house, and dead ends.
+func validateLevel(level *Level) validationReport {
+	report := validationReport{errors: checkLevel(level)}
+	r := tunables.PacmanRadius
+	g := &Game{pacman: Pacman{radius: r}}
+	g.loadMaze(level)
+
+	// Half-tile lattice, fine enough not to miss gaps between tile-aligned walls
+	nav := g.buildNavGrid(tileSize / 2)
+	start, ok := nav.cellAt(g.spawn.x, g.spawn.y)
+	if !ok || !nav.open[start] {
+		return report
+	}
+	reached := nav.reachable(start)
+
+	// Dots are eaten from anywhere within Pacman's radius
+	reach := int(r / nav.step)
+	for _, dot := range g.levelDots() {
+		eaten := false
+		for dy := -reach; dy <= reach && !eaten; dy++ {
+			for dx := -reach; dx <= reach && !eaten; dx++ {
+				i, ok := nav.cellAt(dot.x+float64(dx)*nav.step, dot.y+float64(dy)*nav.step)
+				c := nav.centre(i)
+				eaten = ok && reached[i] && distance(c.x, c.y, dot.x, dot.y) < r
+			}
+		}
+		if !eaten {
+			kind := "dot"
+			if dot.power {
+				kind = "pellet"
+			}
+			report.errorf("%s at tile (%g, %g) is unreachable", kind, dot.x/tileSize, dot.y/tileSize)
+		}
+	}
+
+	if exit, ok := nav.nearestOpen(g.ghostExit.x, g.ghostExit.y); !ok || !reached[exit] {
+		report.errorf("ghost exit at tile (%g, %g) cannot reach pacman", level.GhostExit.Col, level.GhostExit.Row)
+	}
+
+	for _, end := range deadEnds(nav, reached) {
+		c := nav.centre(end)
+		report.warnf("dead end at tile (%g, %g)", c.x/tileSize, c.y/tileSize)
+	}
+
+	// Walls meet and cross all the time; one that adds nothing is a mistake
+	walls := level.Walls
+	for i := range walls {
+		for j := i + 1; j < len(walls); j++ {
+			switch {
+			case walls[i] == walls[j]:
+				report.warnf("walls %d and %d are the same", i+1, j+1)
+			case walls[i].within(walls[j]):
+				report.warnf("wall %d is inside wall %d", i+1, j+1)
+			case walls[j].within(walls[i]):
+				report.warnf("wall %d is inside wall %d", j+1, i+1)
+			}
+		}
+	}
+	return report
+}
+
+// Reached nodes where Pacman can only carry on in one direction. Neighbouring
+// dead end nodes are the same corridor end, so only one of each is returned.
+func deadEnds(nav *navGrid, reached []bool) []int {
+	// Enough room to move a body's radius counts as a way out
+	run := int(tunables.PacmanRadius / nav.step)
+	open := func(i, dCol, dRow int) bool {
+		col, row := i%nav.cols, i/nav.cols
+		for step := 1; step <= run; step++ {
+			// Wrap around, for tunnels; elsewhere the edge is walled off anyway
+			c := (col + dCol*step + nav.cols) % nav.cols
+			r := (row + dRow*step + nav.rows) % nav.rows
+			if !reached[nav.index(c, r)] {
+				return false
+			}
+		}
+		return true
+	}
+
+	isEnd := make([]bool, len(reached))
+	for i := range reached {
+		if !reached[i] {
+			continue
+		}
+		ways, way := 0, [2]int{}
+		for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			if open(i, d[0], d[1]) {
+				ways++
+				way = d
+			}
+		}
+		if ways != 1 {
+			continue
+		}
+		// Backed right up against a wall, otherwise it's just the side of a
+		// corridor wider than the body. And the way out has to be a corridor
+		// too, not a nook by the rounded corner of a wall.
+		col, row := i%nav.cols, i/nav.cols
+		back := nav.index((col-way[0]+nav.cols)%nav.cols, (row-way[1]+nav.rows)%nav.rows)
+		ahead := nav.index((col+way[0]+nav.cols)%nav.cols, (row+way[1]+nav.rows)%nav.rows)
+		side := [2]int{way[1], way[0]}
+		isEnd[i] = !reached[back] && !open(ahead, side[0], side[1]) && !open(ahead, -side[0], -side[1])
+	}
+
+	var ends []int
+	seen := make([]bool, len(reached))
+	for i := range isEnd {
+		if !isEnd[i] || seen[i] {
+			continue
+		}
+		ends = append(ends, i)
+		queue := []int{i}
+		seen[i] = true
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, next := range nav.neighbours(current) {
+				if isEnd[next] && !seen[next] {
+					seen[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return ends
+}
+
+// Whether r lies wholly inside other
+func (r TileRect) within(other TileRect) bool {
+	return r.Col >= other.Col && r.Row >= other.Row &&
+		r.Col+r.Cols <= other.Col+other.Cols && r.Row+r.Rows <= other.Row+other.Rows
+}
+
+// pacman validate [-strict] [-builtin] [level.json...]
+// Exits non-zero if any level has errors, or warnings with -strict.
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	strict := flags.Bool("strict", false, "treat warnings as errors")
+	builtin := flags.Bool("builtin", false, "check the built-in maze")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: pacman validate [-strict] [-builtin] [level.json...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 && !*builtin {
+		flags.Usage()
+		return 2
+	}
+
+	failed := false
+	check := func(name string, level *Level) {
+		report := validateLevel(level)
+		for _, e := range report.errors {
+			fmt.Printf("%s: error: %s\n", name, e)
+		}
+		for _, w := range report.warnings {
+			fmt.Printf("%s: warning: %s\n", name, w)
+		}
+		if len(report.errors) > 0 || (*strict && len(report.warnings) > 0) {
+			failed = true
+		} else {
+			fmt.Printf("%s: ok\n", name)
+		}
+	}
+	if *builtin {
+		check("built-in", &defaultLevel)
+	}
+	for _, path := range flags.Args() {
+		level, err := loadLevel(path)
+		if err != nil {
+			fmt.Printf("%s: error: %v\n", path, err)
+			failed = true
+			continue
+		}
+		check(path, level)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateLevelProblems(t *testing.T) {
+	wall := defaultLevel.Walls[0]
+	tests := []struct {
+		name  string
+		edit  func(l *Level)
+		error string
+	}{
+		{"walled in dot", func(l *Level) {
+			l.Dots = append(l.Dots, TilePoint{Col: wall.Col + wall.Cols/2, Row: wall.Row + wall.Rows/2})
+		}, "dot at tile"},
+		{"walled in pellet", func(l *Level) {
+			l.Pellets = append(l.Pellets, TilePoint{Col: wall.Col + wall.Cols/2, Row: wall.Row + wall.Rows/2})
+		}, "pellet at tile"},
+		{"blocked spawn", func(l *Level) {
+			l.Pacman = TilePoint{Col: wall.Col + wall.Cols/2, Row: wall.Row + wall.Rows/2}
+		}, "pacman spawn is blocked"},
+		{"ghost outside the cage", func(l *Level) {
+			l.Ghosts[0] = l.Pacman
+		}, "ghost 1 spawns outside the cage"},
+		{"no ghosts", func(l *Level) {
+			l.Ghosts = nil
+		}, "no ghost spawns"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			level := defaultLevel.clone()
+			test.edit(level)
+			report := validateLevel(level)
+			if !strings.Contains(strings.Join(report.errors, "\n"), test.error) {
+				t.Errorf("errors %q don't mention %q", report.errors, test.error)
+			}
+		})
+	}
+}
+
+// The levels the game ships with pass -strict
+func TestValidateShippedLevels(t *testing.T) {
+	wide, err := loadLevel("assets/levels/wide.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, level := range map[string]*Level{"built-in": &defaultLevel, "wide": wide} {
+		report := validateLevel(level)
+		if len(report.errors) > 0 || len(report.warnings) > 0 {
+			t.Errorf("%s level has errors %q and warnings %q", name, report.errors, report.warnings)
+		}
+	}
+}
+
+func TestValidateOverlappingWalls(t *testing.T) {
+	wall := defaultLevel.Walls[0]
+	tests := []struct {
+		name    string
+		extra   TileRect
+		warning string
+	}{
+		{"duplicate", wall, "are the same"},
+		{"inside", TileRect{Col: wall.Col, Row: wall.Row, Cols: wall.Cols / 2, Rows: wall.Rows}, "is inside wall 1"},
+		{"crossing", TileRect{Col: wall.Col + wall.Cols/2, Row: wall.Row - 1, Cols: 1, Rows: wall.Rows + 2}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			level := defaultLevel.clone()
+			level.Walls = append(level.Walls, test.extra)
+			report := validateLevel(level)
+			warnings := strings.Join(report.warnings, "\n")
+			switch {
+			case test.warning == "" && strings.Contains(warnings, "wall"):
+				t.Errorf("crossing walls gave warnings %q", report.warnings)
+			case !strings.Contains(warnings, test.warning):
+				t.Errorf("warnings %q don't mention %q", report.warnings, test.warning)
+			}
+		})
+	}
+}
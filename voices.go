@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+)
+
+// voicePool plays one sound on as many players as it needs, so it can start
+// again before it's finished. Players are decoded from the cached file as
+// needed, up to the sound's voice limit; past that the voice started longest
+// ago is cut off and reused.
+type voicePool struct {
+	def      soundDef
+	backend  audioBackend
+	chiptune bool          // Synthesise the sound rather than load it
+	voices   []audioPlayer // Oldest started first
+}
+
+// How many voices a sound can have at once
+func (d soundDef) maxVoices() int {
+	return max(d.voices, 1)
+}
+
+// The sound's stream: its file, or the synthesiser's version when chiptune
+// mode is on or the file won't load
+func (v *voicePool) stream() (audioStream, error) {
+	if v.chiptune {
+		if stream, ok := synthesise(v.def.synth); ok {
+			return stream, nil
+		}
+	}
+	stream, err := loadAudio(v.def.file)
+	if err != nil {
+		if synth, ok := synthesise(v.def.synth); ok {
+			if len(v.voices) == 0 {
+				log.Printf("%v, synthesising it instead", err)
+			}
+			return synth, nil
+		}
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (v *voicePool) newVoice() (audioPlayer, error) {
+	stream, err := v.stream()
+	if err != nil {
+		return nil, err
+	}
+	var src io.ReadSeeker = stream
+	length := stream.Length()
+	if v.def.pitch != 0 && v.def.pitch != 1 {
+		// Resampling from a higher rate than the real one plays it faster,
+		// and so higher
+		from := int(sampleRate * v.def.pitch)
+		src = audio.Resample(src, length, from, sampleRate)
+		if resampled, ok := src.(audioStream); ok {
+			length = resampled.Length()
+		}
+	}
+	if v.def.loop {
+		src = audio.NewInfiniteLoop(src, length)
+		length = 0
+	}
+	p, err := v.backend.newPlayer(src, length)
+	closer, owned := stream.(io.Closer)
+	if err != nil {
+		if owned {
+			closer.Close()
+		}
+		return nil, err
+	}
+	if owned {
+		return streamPlayer{p, closer}, nil
+	}
+	return p, nil
+}
+
+// A player that closes its stream along with itself, as players leave
+// their streams open
+type streamPlayer struct {
+	audioPlayer
+	stream io.Closer
+}
+
+func (p streamPlayer) Close() error {
+	return errors.Join(p.audioPlayer.Close(), p.stream.Close())
+}
+
+// A voice to play the sound on: an idle one, a new one, or the oldest
+func (v *voicePool) voice() (audioPlayer, error) {
+	i := 0
+	for i < len(v.voices) && v.voices[i].IsPlaying() {
+		i++
+	}
+	if i == len(v.voices) {
+		if len(v.voices) < v.def.maxVoices() {
+			p, err := v.newVoice()
+			if err != nil {
+				return nil, err
+			}
+			v.voices = append(v.voices, p)
+			return p, nil
+		}
+		// Steal the voice that's been playing longest
+		i = 0
+	}
+	p := v.voices[i]
+	v.voices = append(append(v.voices[:i:i], v.voices[i+1:]...), p)
+	return p, nil
+}
+
+func (v *voicePool) playing() bool {
+	for _, p := range v.voices {
+		if p.IsPlaying() {
+			return true
+		}
+	}
+	return false
+}
+
+// Pause every voice that's playing, returning them
+func (v *voicePool) pause() []audioPlayer {
+	var paused []audioPlayer
+	for _, p := range v.voices {
+		if p.IsPlaying() {
+			p.Pause()
+			paused = append(paused, p)
+		}
+	}
+	return paused
+}
+
+func (v *voicePool) setVolume(volume float64) {
+	for _, p := range v.voices {
+		p.SetVolume(volume)
+	}
+}
+
+// Free every voice, for good
+func (v *voicePool) close() {
+	for _, p := range v.voices {
+		if err := p.Close(); err != nil {
+			log.Printf("failed to close sound: %v", err)
+		}
+	}
+	v.voices = nil
+}
@@ -2,56 +2,80 @@ package main
 
 import (
 	"image/color"
-	"math/rand"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
 )
 
 type Wall struct {
 	x, y, Width, Height float64
 	Color               color.Color
+	shade               int // Index into the palette's wall colours
 }
 
-var Walls = Map([]Wall{
+// Wall offsets and arm lengths, in tiles
+const (
+	wallOffset = WallMinimumOffset / tileSize
+	wallArm    = WallWidth / tileSize
+)
+
+// Build a wall from a rectangle of tiles
+func tileWall(col, row, cols, rows float64) Wall {
+	return Wall{x: col * tileSize, y: row * tileSize, Width: cols * tileSize, Height: rows * tileSize}
+}
+
+// Walls of the built-in maze
+var defaultWalls = []TileRect{
 	// Outer walls
-	{x: 0, y: 0, Width: screenWidth, Height: wallThickness},                            // Top
-	{x: 0, y: 0, Width: wallThickness, Height: screenHeight},                           // Left
-	{x: screenWidth - wallThickness, y: 0, Width: wallThickness, Height: screenHeight}, // Right
-	{x: 0, y: screenHeight - wallThickness, Width: screenWidth, Height: wallThickness}, // Bottom
+	{0, 0, worldCols, 1},             // Top
+	{0, 0, 1, worldRows},             // Left
+	{worldCols - 1, 0, 1, worldRows}, // Right
+	{0, worldRows - 1, worldCols, 1}, // Bottom
 
 	// Inner walls
 	// 'L' shape on the left side
-	{x: WallMinimumOffset, y: WallMinimumOffset, Width: WallWidth, Height: wallThickness}, // Horizontal part
-	{x: WallMinimumOffset, y: WallMinimumOffset, Width: wallThickness, Height: WallWidth}, // Vertical part
+	{wallOffset, wallOffset, wallArm, 1}, // Horizontal part
+	{wallOffset, wallOffset, 1, wallArm}, // Vertical part
 
 	// 'L' shape on the right side
-	{x: screenWidth - WallMinimumOffset - WallWidth, y: WallMinimumOffset, Width: WallWidth, Height: wallThickness},     // Horizontal part
-	{x: screenWidth - WallMinimumOffset - wallThickness, y: WallMinimumOffset, Width: wallThickness, Height: WallWidth}, // Vertical part
+	{worldCols - wallOffset - wallArm, wallOffset, wallArm, 1}, // Horizontal part
+	{worldCols - wallOffset - 1, wallOffset, 1, wallArm},       // Vertical part
 
 	// 'L' shape on the bottom left side
-	{x: WallMinimumOffset, y: screenHeight - WallMinimumOffset - wallThickness, Width: WallWidth, Height: wallThickness}, // Horizontal part
-	{x: WallMinimumOffset, y: screenHeight - WallMinimumOffset - WallWidth, Width: wallThickness, Height: WallWidth},     // Vertical part
+	{wallOffset, worldRows - wallOffset - 1, wallArm, 1},       // Horizontal part
+	{wallOffset, worldRows - wallOffset - wallArm, 1, wallArm}, // Vertical part
 
 	// 'L' shape on the bottom right side
-	{x: screenWidth - WallMinimumOffset - WallWidth, y: screenHeight - WallMinimumOffset - wallThickness, Width: WallWidth, Height: wallThickness}, // Horizontal part
-	{x: screenWidth - WallMinimumOffset - wallThickness, y: screenHeight - WallMinimumOffset - WallWidth, Width: wallThickness, Height: WallWidth}, // Vertical part
+	{worldCols - wallOffset - wallArm, worldRows - wallOffset - 1, wallArm, 1}, // Horizontal part
+	{worldCols - wallOffset - 1, worldRows - wallOffset - wallArm, 1, wallArm}, // Vertical part
 
-	// Offset 60 from the cage walls horizontally
-	{x: cageX - WallMinimumOffset - wallThickness, y: cageY, Width: wallThickness, Height: cageHeight}, // Left
-	{x: cageX + cageWidth + WallMinimumOffset, y: cageY, Width: wallThickness, Height: cageHeight},     // Right
+	// Offset from the cage walls horizontally
+	{cageCol - wallOffset - 1, cageRow, 1, cageRows},        // Left
+	{cageCol + cageCols + wallOffset, cageRow, 1, cageRows}, // Right
 
-	// Offset 60 from the cage walls vertically
-	{x: cageX, y: cageY - WallMinimumOffset - wallThickness, Width: cageWidth, Height: wallThickness}, // Top
-	{x: cageX, y: cageY + cageHeight + WallMinimumOffset, Width: cageWidth, Height: wallThickness},    // Bottom
+	// Offset from the cage walls vertically
+	{cageCol, cageRow - wallOffset - 1, cageCols, 1},        // Top
+	{cageCol, cageRow + cageRows + wallOffset, cageCols, 1}, // Bottom
 
-}, func(w Wall) Wall {
-	colors := []color.Color{yellow, green, blue, lightBlue, red}
-	w.Color = colors[rand.Intn(len(colors))]
-	return w
-})
+}
+
+// Turn tile rectangles into walls, each given a random colour from the palette
+func buildWalls(rects []TileRect) []Wall {
+	return Map(rects, func(r TileRect) Wall {
+		w := r.wall()
+		colors := classicPalette.Walls
+		w.shade = rng.Intn(len(colors))
+		w.Color = colors[w.shade]
+		return w
+	})
+}
 
 func (w *Wall) Draw(screen *ebiten.Image) {
+	if settings.Accessibility.ThickWalls {
+		vector.StrokeRect(screen, float32(w.x), float32(w.y), float32(w.Width), float32(w.Height), 3, w.Color, false)
+		return
+	}
 	ebitenutil.DrawLine(screen, w.x, w.y, w.x+w.Width, w.y, w.Color)                   // Top
 	ebitenutil.DrawLine(screen, w.x, w.y, w.x, w.y+w.Height, w.Color)                  // Left
 	ebitenutil.DrawLine(screen, w.x+w.Width, w.y, w.x+w.Width, w.y+w.Height, w.Color)  // Right